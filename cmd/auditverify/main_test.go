@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tramamte/go-foundation/pkg/logger"
+)
+
+func TestRun(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "audit.log")
+
+	l := logger.New("audit", false)
+	c := logger.NewAuditAdapterConfig()
+	c.Filename = name
+	c.Key = []byte("secret")
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	l.Information("one")
+	l.Information("two")
+	l.Detach(logger.AdapterAudit)
+
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"-key", "736563726574", name}, &stdout, &stderr); status != 0 {
+		t.Fatalf("status %d: %s", status, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "2 records in 1 files verified") {
+		t.Errorf("got %q", stdout.String())
+	}
+
+	// tampered
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, bytes.Replace(b, []byte("one"), []byte("ONE"), 1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stderr.Reset()
+	if status := run([]string{"-key", "736563726574", name}, &stdout, &stderr); status != 1 {
+		t.Errorf("tampered: status %d", status)
+	}
+	if !strings.Contains(stderr.String(), name+":1: hash mismatch") {
+		t.Errorf("tampered: got %q", stderr.String())
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"-key", "zz", name},
+		{"-key", "00", "-keyfile", name, name},
+	} {
+		if status := run(args, &stdout, &stderr); status != 2 {
+			t.Errorf("%v: status %d", args, status)
+		}
+	}
+}
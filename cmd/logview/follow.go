@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// follower reads a growing file and reopens it when rotated or truncated.
+// It returns complete lines only, and io.EOF when no line is available yet.
+type follower struct {
+	name   string
+	file   *os.File
+	offset int64
+	buf    []byte // read but not returned, a partial line at most
+	chunk  []byte
+}
+
+func newFollower(name string) (*follower, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &follower{name: name, file: f, chunk: make([]byte, 32*1024)}, nil
+}
+
+func (f *follower) Read(p []byte) (int, error) {
+	for {
+		if i := bytes.LastIndexByte(f.buf, '\n'); i >= 0 {
+			n := copy(p, f.buf[:i+1])
+			f.buf = f.buf[:copy(f.buf, f.buf[n:])]
+			return n, nil
+		}
+
+		n, err := f.file.Read(f.chunk)
+		f.offset += int64(n)
+		f.buf = append(f.buf, f.chunk[:n]...)
+		if n > 0 {
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		switched, err := f.reopen()
+		if err != nil {
+			return 0, err
+		}
+		if !switched {
+			return 0, io.EOF
+		}
+	}
+}
+
+// reopen switches to a new file at the name, or rewinds a truncated file.
+// It is called at the end of the current file.
+func (f *follower) reopen() (bool, error) {
+	fi, err := os.Stat(f.name)
+	if os.IsNotExist(err) {
+		// moved away, not created yet
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	cur, err := f.file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	if !os.SameFile(fi, cur) {
+		file, err := os.Open(f.name)
+		if err != nil {
+			return false, nil
+		}
+		f.file.Close()
+		f.file = file
+	} else if fi.Size() < f.offset {
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+	} else {
+		return false, nil
+	}
+
+	// the last line of the previous file will never be completed
+	if len(f.buf) > 0 {
+		f.buf = append(f.buf, '\n')
+	}
+	f.offset = 0
+	return true, nil
+}
+
+func (f *follower) Close() error {
+	return f.file.Close()
+}
@@ -0,0 +1,357 @@
+// Command logview reads log files written by the logger package,
+// filters records and colorizes them by level.
+//
+// Usage:
+//
+//	logview [flags] [file ...]
+//
+// Without files or with "-", it reads standard input.
+// Rotated siblings of a file (file.YYYYMMDD, gzip compressed or not)
+// are read before the file. With -f, the last file is followed
+// for appended records, across rotation.
+//
+// Encrypted logs are read with -key or -keyfile, and -decrypt
+// writes their text as it is, without parsing records.
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tramamte/go-foundation/pkg/logger"
+)
+
+// interval of polling a followed file
+const pollInterval = 200 * time.Millisecond
+
+type options struct {
+	config  *logger.ReaderConfig
+	filter  logger.Filter
+	color   bool
+	follow  bool
+	rotated bool
+	decrypt bool
+}
+
+func main() {
+	opts, files, err := parseFlags(os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "logview:", err)
+		os.Exit(2)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for i, name := range files {
+		follow := opts.follow && i == len(files)-1
+		if err := view(out, name, opts, follow); err != nil {
+			out.Flush()
+			fmt.Fprintln(os.Stderr, "logview:", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func parseFlags(args []string) (*options, []string, error) {
+	fs := flag.NewFlagSet("logview", flag.ContinueOnError)
+	var (
+		format  = fs.String("format", logger.DefaultFormat, "format of the log, see logger.DefaultFormat")
+		json    = fs.Bool("json", false, "the log is JSON encoding")
+		tz      = fs.String("tz", "", "time zone of the log, local if empty")
+		level   = fs.String("level", "", "minimum level, name or short code")
+		since   = fs.String("since", "", "records at or after time, RFC3339, \"2006-01-02 15:04:05\" or duration ago such as 1h")
+		until   = fs.String("until", "", "records before time, same as -since")
+		name    = fs.String("name", "", "comma separated logger name patterns, e.g. \"Default.db*\"")
+		grep    = fs.String("grep", "", "regular expression matching messages")
+		color   = fs.String("color", "auto", "colorize records: auto, always or never")
+		follow  = fs.Bool("f", false, "follow the last file")
+		rotated = fs.Bool("rotated", true, "read rotated siblings of files")
+		keyHex  = fs.String("key", "", "AES key of encrypted logs in hex")
+		keyFile = fs.String("keyfile", "", "file containing raw AES key bytes")
+		decrypt = fs.Bool("decrypt", false, "write decrypted text of encrypted logs without filtering")
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: logview [flags] [file ...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	opts := &options{
+		config:  logger.NewReaderConfig(),
+		follow:  *follow,
+		rotated: *rotated,
+		decrypt: *decrypt,
+	}
+	opts.config.Format = *format
+	opts.config.TimeZone = *tz
+	if *json {
+		opts.config.Encoding = logger.EncodingJSON
+	}
+
+	key, err := loadKey(*keyHex, *keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts.config.Key = key
+	if opts.decrypt && key == nil {
+		return nil, nil, fmt.Errorf("-decrypt needs -key or -keyfile")
+	}
+	if opts.follow && key != nil {
+		return nil, nil, fmt.Errorf("encrypted logs can't be followed")
+	}
+
+	switch *color {
+	case "auto":
+		opts.color = logger.ColorSupported(os.Stdout)
+	case "always":
+		opts.color = true
+	case "never":
+	default:
+		return nil, nil, fmt.Errorf("invalid -color %q", *color)
+	}
+
+	var filters []logger.Filter
+	if len(*level) > 0 {
+		l, ok := lookupLevel(*level)
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown level %q", *level)
+		}
+		filters = append(filters, logger.MinLevel(l))
+	}
+	for _, t := range []struct {
+		value  string
+		before bool
+	}{{*since, false}, {*until, true}} {
+		if len(t.value) == 0 {
+			continue
+		}
+		tm, err := parseTime(t.value)
+		if err != nil {
+			return nil, nil, err
+		}
+		before := t.before
+		filters = append(filters, logger.FilterFunc(func(r *logger.Record) bool {
+			return r.Time.Before(tm) == before
+		}))
+	}
+	if len(*name) > 0 {
+		filters = append(filters, logger.NameIn(strings.Split(*name, ",")...))
+	}
+	if len(*grep) > 0 {
+		re, err := regexp.Compile(*grep)
+		if err != nil {
+			return nil, nil, err
+		}
+		filters = append(filters, logger.MessageMatch(re))
+	}
+	opts.filter = logger.And(filters...)
+
+	return opts, fs.Args(), nil
+}
+
+func loadKey(keyHex, keyFile string) ([]byte, error) {
+	switch {
+	case len(keyHex) > 0 && len(keyFile) > 0:
+		return nil, fmt.Errorf("both -key and -keyfile are given")
+	case len(keyHex) > 0:
+		return hex.DecodeString(keyHex)
+	case len(keyFile) > 0:
+		return os.ReadFile(keyFile)
+	}
+	return nil, nil
+}
+
+// lookupLevel finds a level by name or short code.
+func lookupLevel(s string) (logger.Level, bool) {
+	for _, l := range logger.Levels() {
+		spec, _ := logger.LookupLevel(l)
+		if strings.EqualFold(s, spec.Name) || strings.EqualFold(s, spec.Short) {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
+// parseTime parses an absolute time or a duration before now.
+func parseTime(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// view writes records of a file and its rotated siblings.
+func view(out *bufio.Writer, name string, opts *options, follow bool) error {
+	if name == "-" {
+		if opts.decrypt {
+			return decrypt(out, os.Stdin, "stdin", opts.config.Key)
+		}
+		rd, err := logger.NewReader(os.Stdin, opts.config)
+		if err != nil {
+			return err
+		}
+		return copyRecords(out, rd, "stdin", opts, false)
+	}
+
+	if opts.rotated {
+		for _, sibling := range rotatedSiblings(name) {
+			if err := viewFile(out, sibling, opts); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !follow {
+		return viewFile(out, name, opts)
+	}
+
+	f, err := newFollower(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	config := *opts.config
+	config.Follow = true
+	rd, err := logger.NewReader(f, &config)
+	if err != nil {
+		return err
+	}
+	return copyRecords(out, rd, name, opts, true)
+}
+
+func viewFile(out *bufio.Writer, name string, opts *options) error {
+	if opts.decrypt {
+		return decryptFile(out, name, opts.config.Key)
+	}
+
+	rd, err := logger.OpenReader(name, opts.config)
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+	return copyRecords(out, rd, name, opts, false)
+}
+
+// decryptFile writes text of an encrypted log file.
+func decryptFile(out *bufio.Writer, name string, key []byte) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(name, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	return decrypt(out, r, name, key)
+}
+
+// decrypt writes text of an encrypted log.
+// A truncated frame at the end is reported and skipped.
+func decrypt(out *bufio.Writer, r io.Reader, name string, key []byte) error {
+	dr, err := logger.NewDecryptReader(r, key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if _, err := io.Copy(out, dr); err != nil {
+		if errors.Is(err, logger.ErrTruncatedFrame) {
+			fmt.Fprintf(os.Stderr, "logview: %s: %v\n", name, err)
+			return nil
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// rotatedSiblings returns name.YYYYMMDD files oldest first.
+func rotatedSiblings(name string) []string {
+	matches, _ := filepath.Glob(name + ".[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]*")
+	var files []string
+	for _, m := range matches {
+		suffix := strings.TrimPrefix(m, name+".")
+		if len(suffix) == 8 || suffix[8:] == ".gz" {
+			files = append(files, m)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func copyRecords(out *bufio.Writer, rd *logger.Reader, name string, opts *options, follow bool) error {
+	palette := logger.DefaultPalette()
+	idle := false
+	for {
+		rec, err := rd.Read()
+		if err == io.EOF {
+			if !follow {
+				return nil
+			}
+			// the last record is complete when the file doesn't grow for a poll
+			if idle {
+				rec, err = rd.Flush()
+			}
+			if err == io.EOF {
+				if err := out.Flush(); err != nil {
+					return err
+				}
+				time.Sleep(pollInterval)
+				idle = true
+				continue
+			}
+		}
+		idle = false
+		if errors.Is(err, logger.ErrInvalidRecord) {
+			fmt.Fprintf(os.Stderr, "logview: %s: %v\n", name, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if !opts.filter.Match(rec) {
+			continue
+		}
+		if color := palette[rec.Level]; opts.color && len(color) > 0 {
+			out.WriteString(color)
+			out.WriteString(rd.Text())
+			out.WriteString(logger.ColorReset)
+		} else {
+			out.WriteString(rd.Text())
+		}
+		if err := out.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tramamte/go-foundation/pkg/logger"
+)
+
+func TestFollowerRotation(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(name, []byte("one\ntw"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := newFollower(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	read := func() string {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	if got := read(); got != "one\n" {
+		t.Errorf("partial line: got %q", got)
+	}
+
+	// rotate and write the new file
+	if err := os.Rename(name, name+".20210304"); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(); got != "" {
+		t.Errorf("moved away: got %q", got)
+	}
+	if err := os.WriteFile(name, []byte("three\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(); got != "tw\nthree\n" {
+		t.Errorf("rotated: got %q", got)
+	}
+
+	// truncate
+	if err := os.WriteFile(name, []byte("4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(); got != "4\n" {
+		t.Errorf("truncated: got %q", got)
+	}
+}
+
+func TestView(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	files := map[string]string{
+		name + ".20210303": "2021-03-03 10:00:00.000 [ERR] old (a.go:1)\n",
+		name + ".20210304": "2021-03-04 10:00:00.000 [INF] skipped (a.go:2)\n",
+		name:               "2021-03-05 10:00:00.000 [WRN] new\nline (a.go:3)\n",
+		name + ".bak":      "2021-03-01 10:00:00.000 [ERR] ignored (a.go:4)\n",
+	}
+	for n, text := range files {
+		if err := os.WriteFile(n, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts, args, err := parseFlags([]string{"-level", "warning", "-tz", "UTC", "-color", "never", name})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	if err := view(out, args[0], opts, false); err != nil {
+		t.Fatal(err)
+	}
+	out.Flush()
+
+	want := "2021-03-03 10:00:00.000 [ERR] old (a.go:1)\n" +
+		"2021-03-05 10:00:00.000 [WRN] new\nline (a.go:3)\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestViewEncrypted(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	key := bytes.Repeat([]byte{7}, 16)
+
+	l := logger.New("app", false)
+	c := logger.NewFileAdapterConfig()
+	c.Filename = name
+	c.Format = "$slevel $msg"
+	c.EncryptionKey = key
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	l.Information("info")
+	l.Error("error")
+	l.Detach(logger.AdapterFile)
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-format", "$slevel $msg", "-level", "error"}, "ERR error\n"},
+		{[]string{"-decrypt"}, "INF info\nERR error\n"},
+	} {
+		args := append([]string{"-key", hex.EncodeToString(key), "-color", "never"}, tc.args...)
+		opts, _, err := parseFlags(append(args, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		out := bufio.NewWriter(&buf)
+		if err := view(out, name, opts, false); err != nil {
+			t.Fatal(err)
+		}
+		out.Flush()
+		if buf.String() != tc.want {
+			t.Errorf("%v: got %q, want %q", tc.args, buf.String(), tc.want)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrAuditBroken is returned when the hash chain of an audit log doesn't verify.
+var ErrAuditBroken = errors.New("audit log chain broken")
+
+// AuditAdapterConfig structure
+// An audit adapter is a file adapter which appends to each record
+// a hash chaining it to the previous record:
+//
+//	<record> <hex of SHA-256(previous hash + record)>
+//
+// With Key, HMAC-SHA-256 is used instead, so that the chain can't be
+// rebuilt without the key. The chain continues across rotated files,
+// and VerifyAudit detects modified, inserted or deleted records and files.
+// Records are always written in a line, MultiLine is MultiLineEscape
+// for text encoding, and Truncate and EncryptionKey are not allowed.
+//
+// Records buffered but not written by a failure are lost, and the chain
+// continues from the last record in the files when the file is opened
+// again. A partial last line left by a crash or a failed write can't be
+// verified, and it's cut off from the file when the file is opened.
+type AuditAdapterConfig struct {
+	FileAdapterConfig
+	Key []byte // HMAC key, nil for plain hash
+}
+
+// NewAuditAdapterConfig returns a new AuditAdapterConfig instance.
+func NewAuditAdapterConfig() *AuditAdapterConfig {
+	c := &AuditAdapterConfig{FileAdapterConfig: *NewFileAdapterConfig()}
+	c.MultiLine = MultiLineEscape
+	return c
+}
+
+func (c *AuditAdapterConfig) id() AdapterID {
+	return AdapterAudit
+}
+
+///////////////////////////////////////////////////////////////////////
+
+// size of hash and its hex in a line
+const (
+	auditHashSize = sha256.Size
+	auditHexSize  = 2 * auditHashSize
+)
+
+type auditAdapter struct {
+	fileAdapter
+	mac  hash.Hash
+	prev []byte // hash of the last record written
+	next []byte // hash of the line sealed, not written yet
+}
+
+func newAuditAdapter() adapter {
+	return &auditAdapter{}
+}
+
+func (a *auditAdapter) id() AdapterID {
+	return AdapterAudit
+}
+
+func (a *auditAdapter) init(c AdapterConfig) error {
+	cc, ok := c.(*AuditAdapterConfig)
+	if !ok {
+		return ErrInvalidConfig
+	}
+	if cc.Truncate || cc.EncryptionKey != nil {
+		return ErrInvalidConfig
+	}
+
+	fc := cc.FileAdapterConfig
+	if fc.Encoding == EncodingText {
+		fc.MultiLine = MultiLineEscape
+	}
+
+	if err := a.recover(fc.Filename); err != nil {
+		return err
+	}
+
+	a.mac = newAuditHash(cc.Key)
+	a.sealer = a
+	return a.fileAdapter.init(&fc)
+}
+
+// seal appends line and its chained hash to b.
+func (a *auditAdapter) seal(b, line []byte) []byte {
+	record := bytes.TrimSuffix(line, []byte(lineFeed))
+	a.next = chainHash(a.mac, a.prev, record)
+
+	b = append(b, record...)
+	b = append(b, ' ')
+	b = append(b, make([]byte, auditHexSize)...)
+	hex.Encode(b[len(b)-auditHexSize:], a.next)
+	return append(b, lineFeed...)
+}
+
+func (a *auditAdapter) commit() {
+	a.prev = a.next
+}
+
+// recover cuts off a partial last line of the file,
+// and continues the chain from the last record written.
+func (a *auditAdapter) recover(name string) error {
+	if err := cutPartialLine(name); err != nil {
+		return err
+	}
+	prev, err := lastAuditHash(name)
+	if err != nil {
+		return err
+	}
+	a.prev = prev
+	return nil
+}
+
+func newAuditHash(key []byte) hash.Hash {
+	if len(key) > 0 {
+		return hmac.New(sha256.New, key)
+	}
+	return sha256.New()
+}
+
+func chainHash(h hash.Hash, prev, record []byte) []byte {
+	h.Reset()
+	h.Write(prev)
+	h.Write(record)
+	return h.Sum(nil)
+}
+
+// splitAuditLine splits a line into record and hash.
+func splitAuditLine(line string) (record string, sum []byte, ok bool) {
+	if len(line) < auditHexSize+1 || line[len(line)-auditHexSize-1] != ' ' {
+		return "", nil, false
+	}
+	sum, err := hex.DecodeString(line[len(line)-auditHexSize:])
+	if err != nil {
+		return "", nil, false
+	}
+	return line[:len(line)-auditHexSize-1], sum, true
+}
+
+// lastAuditHash returns the hash of the last record of an audit log,
+// in the file or the newest rotated file, to continue the chain.
+// The first hash of a chain is all zero.
+func lastAuditHash(name string) ([]byte, error) {
+	files, err := AuditFiles(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		line, _, err := lastLine(files[i])
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			continue
+		}
+		_, sum, ok := splitAuditLine(line)
+		if !ok {
+			return nil, fmt.Errorf("%s: last line: %w", files[i], ErrAuditBroken)
+		}
+		return sum, nil
+	}
+	return make([]byte, auditHashSize), nil
+}
+
+// cutPartialLine truncates a file ending without a line feed
+// after its last line feed.
+func cutPartialLine(name string) error {
+	_, end, err := lastLine(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	if end < fi.Size() {
+		return os.Truncate(name, end)
+	}
+	return nil
+}
+
+// lastLine returns the last complete line of a file without line feed,
+// and the offset after the line, which is less than the size of the file
+// when the file ends with a partial line. Compressed files are read through,
+// and the offset is 0 for them.
+func lastLine(name string) (string, int64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	if isGzipName(name) {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return "", 0, err
+		}
+		defer zr.Close()
+
+		var last string
+		r := bufio.NewReader(zr)
+		for {
+			line, err := r.ReadString('\n')
+			if err == io.EOF {
+				return last, 0, nil
+			}
+			if err != nil {
+				return "", 0, err
+			}
+			last = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		}
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+	size := fi.Size()
+
+	// read backward until a line feed before the last one
+	for chunk := int64(4096); ; chunk *= 2 {
+		if chunk > size {
+			chunk = size
+		}
+		tail := make([]byte, chunk)
+		if _, err := f.ReadAt(tail, size-chunk); err != nil && err != io.EOF {
+			return "", 0, err
+		}
+
+		end := bytes.LastIndexByte(tail, '\n')
+		if end < 0 && chunk == size {
+			// no complete line
+			return "", 0, nil
+		}
+		if end < 0 {
+			continue
+		}
+		if start := bytes.LastIndexByte(tail[:end], '\n'); start >= 0 || chunk == size {
+			line := strings.TrimSuffix(string(tail[start+1:end]), "\r")
+			return line, size - chunk + int64(end) + 1, nil
+		}
+	}
+}
+
+func isGzipName(name string) bool {
+	return strings.HasSuffix(name, ".gz")
+}
+
+///////////////////////////////////////////////////////////////////////
+// verification
+///////////////////////////////////////////////////////////////////////
+
+// AuditFiles returns rotated files of an audit log and the file itself
+// if exists, oldest first. Rotated files are name.SUFFIX, such as
+// name.20210304 by the adapter, or name.1 and name.2.gz by an external
+// rotator, ordered by modification time, so they have to keep it.
+func AuditFiles(name string) ([]string, error) {
+	matches, err := filepath.Glob(name + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	type rotated struct {
+		name    string
+		modTime time.Time
+	}
+	var files []rotated
+	for _, m := range matches {
+		fi, err := os.Stat(m)
+		if err != nil {
+			return nil, err
+		}
+		if fi.Mode().IsRegular() {
+			files = append(files, rotated{m, fi.ModTime()})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].modTime.Equal(files[j].modTime) {
+			return files[i].modTime.Before(files[j].modTime)
+		}
+		return files[i].name < files[j].name
+	})
+
+	names := make([]string, 0, len(files)+1)
+	for _, f := range files {
+		names = append(names, f.name)
+	}
+	if _, err := os.Stat(name); err == nil {
+		names = append(names, name)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return names, nil
+}
+
+// VerifyAudit verifies the hash chain of audit log files given oldest first,
+// e.g. by AuditFiles, with the key of the adapter.
+// It returns the number of records verified, and an error wrapping
+// ErrAuditBroken with the file and line of the first broken record.
+// Deleting records at the end of the last file can't be detected by the chain,
+// compare the count or the last hash with a copy kept elsewhere.
+func VerifyAudit(files []string, key []byte) (int, error) {
+	h := newAuditHash(key)
+	prev := make([]byte, auditHashSize)
+	count := 0
+
+	for _, name := range files {
+		n, last, err := verifyAuditFile(name, h, prev)
+		count += n
+		if err != nil {
+			return count, err
+		}
+		prev = last
+	}
+	return count, nil
+}
+
+func verifyAuditFile(name string, h hash.Hash, prev []byte) (int, []byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+
+	var rd io.Reader = f
+	if isGzipName(name) {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return 0, nil, err
+		}
+		defer zr.Close()
+		rd = zr
+	}
+
+	r := bufio.NewReader(rd)
+	count := 0
+	for lineNo := 1; ; lineNo++ {
+		line, err := r.ReadString('\n')
+		if err == io.EOF && len(line) == 0 {
+			return count, prev, nil
+		}
+		if err != nil && err != io.EOF {
+			return count, nil, err
+		}
+		if !strings.HasSuffix(line, "\n") {
+			return count, nil, fmt.Errorf("%s:%d: partial line: %w", name, lineNo, ErrAuditBroken)
+		}
+
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		record, sum, ok := splitAuditLine(line)
+		if !ok {
+			return count, nil, fmt.Errorf("%s:%d: no hash: %w", name, lineNo, ErrAuditBroken)
+		}
+		if !hmac.Equal(sum, chainHash(h, prev, []byte(record))) {
+			return count, nil, fmt.Errorf("%s:%d: hash mismatch: %w", name, lineNo, ErrAuditBroken)
+		}
+		prev = sum
+		count++
+	}
+}
@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditAdapter(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+	key := []byte("secret")
+
+	logAudit := func(msgs ...string) {
+		t.Helper()
+		l := New("audit", false)
+		c := NewAuditAdapterConfig()
+		c.Filename = filename
+		c.Key = key
+		if err := l.Attach(c); err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range msgs {
+			l.Information(m)
+		}
+		l.Detach(AdapterAudit)
+	}
+
+	logAudit("a", "multi\nline")
+	// the chain continues after restart and rotation
+	if err := os.Rename(filename, filename+".20210304"); err != nil {
+		t.Fatal(err)
+	}
+	logAudit("b")
+	logAudit("c")
+
+	files, err := AuditFiles(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := VerifyAudit(files, key); err != nil || n != 4 {
+		t.Fatalf("got %d, %v", n, err)
+	}
+	if _, err := VerifyAudit(files, []byte("wrong")); !errors.Is(err, ErrAuditBroken) {
+		t.Errorf("wrong key: got %v", err)
+	}
+	if _, err := VerifyAudit(files[1:], key); !errors.Is(err, ErrAuditBroken) {
+		t.Errorf("deleted file: got %v", err)
+	}
+
+	rotated := readFile(t, files[0])
+	for name, tampered := range map[string]string{
+		"modified": strings.Replace(rotated, "[INF] a", "[INF] x", 1),
+		"deleted":  rotated[strings.Index(rotated, lineFeed)+len(lineFeed):],
+	} {
+		if err := os.WriteFile(files[0], []byte(tampered), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := VerifyAudit(files, key)
+		if !errors.Is(err, ErrAuditBroken) || !strings.Contains(err.Error(), files[0]+":1:") {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+}
+
+func TestAuditAdapterRecovery(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+
+	l := New("audit", false)
+	c := NewAuditAdapterConfig()
+	c.Filename = filename
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	l.Information("written")
+	l.Flush()
+
+	// a failed flush discards buffered records
+	l.Information("lost")
+	a := l.loadAdapters()[0].(*auditAdapter)
+	a.lock.Lock()
+	a.file.Close()
+	a.fail(errors.New("disk full"))
+	a.retryAt = time.Time{}
+	a.lock.Unlock()
+
+	l.Information("after failure")
+	l.Detach(AdapterAudit)
+
+	// a partial line left by a crash
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("2021-03-04 10:00:00.000 [INF] partia")
+	f.Close()
+
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	l.Information("after crash")
+	l.Detach(AdapterAudit)
+
+	if n, err := VerifyAudit([]string{filename}, nil); err != nil || n != 3 {
+		t.Errorf("got %d, %v", n, err)
+	}
+	if got := readFile(t, filename); strings.Contains(got, "lost") || strings.Contains(got, "partia") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestAuditFilesExternalRotation(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+
+	l := New("audit", false)
+	c := NewAuditAdapterConfig()
+	c.Filename = filename
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Detach(AdapterAudit)
+
+	// rotated as audit.log.2.gz and audit.log.1
+	for i, rotated := range []string{filename + ".2.gz", filename + ".1"} {
+		l.Information("record")
+		if err := os.Rename(filename, rotated); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Reopen(); err != nil {
+			t.Fatal(err)
+		}
+		if rotated == filename+".2.gz" {
+			b := readFile(t, rotated)
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			zw.Write([]byte(b))
+			zw.Close()
+			if err := os.WriteFile(rotated, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		mtime := time.Now().Add(time.Duration(i-2) * time.Hour)
+		if err := os.Chtimes(rotated, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Information("record")
+	l.Flush()
+
+	files, err := AuditFiles(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filename + ".2.gz", filename + ".1", filename}
+	if strings.Join(files, ",") != strings.Join(want, ",") {
+		t.Fatalf("got %v", files)
+	}
+	if n, err := VerifyAudit(files, nil); err != nil || n != 3 {
+		t.Errorf("got %d, %v", n, err)
+	}
+}
@@ -3,31 +3,46 @@ package logger
 import (
 	"io"
 	"os"
-	"runtime"
 	"sync"
 )
 
 // ConsoleAdapterConfig sturcture
 type ConsoleAdapterConfig struct {
 	Level     Level
-	Color     bool
+	Color     bool // write ANSI colors
+	ColorAuto bool // write colors only to writers which support them, see ColorSupported
 	Format    string
+	Encoding  Encoding
 	MaxLength uint32
+	TimeZone  string // "" for local, "UTC" or IANA time zone name
+	Filter    Filter // nil for all records above Level
+
+	MultiLine  MultiLine // how line breaks in a text record are written
+	LineMarker string    // prefix of continuation lines for MultiLineIndent
+
+	Writer      io.Writer        // nil for os.Stdout
+	Stderr      bool             // write records of StderrLevel and above to ErrWriter
+	StderrLevel Level            // LevelWarning by default
+	ErrWriter   io.Writer        // nil for os.Stderr
+	Palette     map[Level]string // ANSI color per level, colors of LevelSpec for others
 }
 
 // NewConsoleAdapterConfig returns a new ConsoleAdapterConfig instance.
+// Color and ColorAuto are enabled, so that colors are written to
+// Writer and ErrWriter when they are terminals, and NO_COLOR and
+// FORCE_COLOR environment variables override it. Disable ColorAuto
+// to write colors to any writer.
 func NewConsoleAdapterConfig() *ConsoleAdapterConfig {
-	color := true
-	if runtime.GOOS == "windows" {
-		// ANSI color code is not supported in windows' default terminal
-		color = false
-	}
-
 	return &ConsoleAdapterConfig{
-		Level:     LevelDebug,
-		Color:     color,
-		Format:    DefaultFormat,
-		MaxLength: 0,
+		Level:       LevelDebug,
+		Color:       true,
+		ColorAuto:   true,
+		Format:      DefaultFormat,
+		Encoding:    EncodingText,
+		MaxLength:   0,
+		StderrLevel: LevelWarning,
+		MultiLine:   MultiLineKeep,
+		LineMarker:  DefaultLineMarker,
 	}
 }
 
@@ -38,10 +53,14 @@ func (c *ConsoleAdapterConfig) id() AdapterID {
 ///////////////////////////////////////////////////////////////////////
 
 type consoleAdapter struct {
-	lock   sync.RWMutex
-	writer io.Writer
-	config ConsoleAdapterConfig
-	w      logWriter
+	lock      sync.Mutex // custom writer may not be thread safe
+	writer    io.Writer
+	errWriter io.Writer
+	color     bool             // colors to writer
+	errColor  bool             // colors to errWriter
+	palette   map[Level]string // overrides colors of LevelSpec
+	config    ConsoleAdapterConfig
+	enc       *encoder
 }
 
 func newConsoleAdapter() adapter {
@@ -58,19 +77,42 @@ func (a *consoleAdapter) init(c AdapterConfig) error {
 		return ErrInvalidConfig
 	}
 
-	if cc.Level < LevelDebug || cc.Level > LevelFatal {
+	if !cc.Level.valid() || (cc.Stderr && !cc.StderrLevel.valid()) {
 		return ErrInvalidLevel
 	}
 
-	a.writer = os.Stdout
-	a.config = *cc // deep copy
+	enc, err := makeEncoder(writerConfig{
+		format:     cc.Format,
+		encoding:   cc.Encoding,
+		maxMsgLen:  cc.MaxLength,
+		timeZone:   cc.TimeZone,
+		multiLine:  cc.MultiLine,
+		lineMarker: cc.LineMarker,
+	})
+	if err != nil {
+		return err
+	}
 
-	var prefix, suffix string
-	if cc.Color {
-		prefix = "%s"
-		suffix = "%s"
+	a.writer = cc.Writer
+	if a.writer == nil {
+		a.writer = os.Stdout
+	}
+	a.errWriter = cc.ErrWriter
+	if a.errWriter == nil {
+		a.errWriter = os.Stderr
 	}
-	a.w = makeWriter(cc.Format, cc.MaxLength, prefix, suffix)
+
+	a.color = cc.Color && (!cc.ColorAuto || colorSupported(a.writer))
+	a.errColor = cc.Color && (!cc.ColorAuto || colorSupported(a.errWriter))
+
+	// levels registered later get colors of their LevelSpec
+	a.palette = make(map[Level]string, len(cc.Palette))
+	for l, color := range cc.Palette {
+		a.palette[l] = color
+	}
+
+	a.config = *cc // deep copy
+	a.enc = enc
 	return nil
 }
 
@@ -79,43 +121,68 @@ func (a *consoleAdapter) uninit() {
 	defer a.lock.Unlock()
 
 	a.writer = nil
-	a.w = nil
+	a.errWriter = nil
+	a.enc = nil
 }
 
-func (a *consoleAdapter) write(msg *message) {
-	a.lock.RLock()
-	defer a.lock.RUnlock()
+func (a *consoleAdapter) write(msg *message) (bool, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
 
 	if a.writer == nil {
-		return
+		return false, nil
 	}
 
-	if a.config.Level > msg.level {
-		return
+	if !msg.Level.atLeast(a.config.Level) {
+		return false, nil
 	}
 
-	var prefix, suffix interface{}
-	if a.config.Color {
-		switch msg.level {
-		case LevelDebug:
-			prefix = prefixCyan
-		case LevelVerbose:
-			prefix = ""
-		case LevelInformation:
-			prefix = prefixLGreen
-		case LevelWarning:
-			prefix = prefixLYellow
-		case LevelError:
-			prefix = prefixLRed
-		case LevelPanic:
-			prefix = prefixBPurble
-		case LevelFatal:
-			prefix = prefixBRed
-		}
-		suffix = suffixReset
+	if a.config.Filter != nil && !a.config.Filter.Match(&msg.Record) {
+		return false, nil
 	}
-	a.w(a.writer, msg, prefix, suffix)
+
+	line := msg.encode(a.enc)
+	if len(line) == 0 {
+		return false, nil
+	}
+
+	w, color := a.writer, a.color
+	if a.config.Stderr && msg.Level.atLeast(a.config.StderrLevel) {
+		w, color = a.errWriter, a.errColor
+	}
+
+	if !color {
+		_, err := w.Write(line)
+		return err == nil, err
+	}
+
+	// reset color before the last line feed
+	end := len(line) - len(lineFeed)
+	buf := getBuffer()
+	if c, ok := a.palette[msg.Level]; ok {
+		buf.b = append(buf.b, c...)
+	} else {
+		buf.b = append(buf.b, levelSpecs()[msg.Level].Color...)
+	}
+	buf.b = append(buf.b, line[:end]...)
+	buf.b = append(buf.b, suffixReset...)
+	buf.b = append(buf.b, line[end:]...)
+	_, err := w.Write(buf.b)
+	putBuffer(buf)
+	return err == nil, err
+}
+
+func (a *consoleAdapter) level() Level {
+	return a.config.Level
 }
 
-func (a *consoleAdapter) flush() {
+func (a *consoleAdapter) captures() capture {
+	if a.enc == nil {
+		return 0
+	}
+	return a.enc.caps
+}
+
+func (a *consoleAdapter) flush() error {
+	return nil
 }
@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestConsoleAdapterWriters(t *testing.T) {
+	var out, errOut bytes.Buffer
+
+	l := New("console", false)
+	c := NewConsoleAdapterConfig()
+	c.Format = "$msg"
+	c.Writer = &out
+	c.ErrWriter = &errOut
+	c.Stderr = true
+	c.ColorAuto = false
+	c.Palette = map[Level]string{LevelWarning: "<w>"}
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Information("info")
+	l.Warning("warn")
+
+	if got, want := out.String(), prefixLGreen+"info"+suffixReset+lineFeed; got != want {
+		t.Errorf("stdout: got %q, want %q", got, want)
+	}
+	if got, want := errOut.String(), "<w>warn"+suffixReset+lineFeed; got != want {
+		t.Errorf("stderr: got %q, want %q", got, want)
+	}
+}
+
+func TestConsoleAdapterColorAuto(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "0")
+
+	var out bytes.Buffer
+	l := New("console", false)
+	c := NewConsoleAdapterConfig()
+	c.Format = "$msg"
+	c.Writer = &out
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	l.Information("plain")
+	l.Detach(AdapterConsole)
+
+	t.Setenv("FORCE_COLOR", "1")
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	l.Information("colored")
+
+	if got, want := out.String(), "plain"+lineFeed+prefixLGreen+"colored"+suffixReset+lineFeed; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if isTerminal(f) {
+		t.Errorf("%s: got terminal", os.DevNull)
+	}
+}
+
+func TestConsoleAdapterRegisteredColor(t *testing.T) {
+	restoreLevels(t)
+
+	var out bytes.Buffer
+	l := New("console", false)
+	c := NewConsoleAdapterConfig()
+	c.Format = "$msg"
+	c.Writer = &out
+	c.ColorAuto = false
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+
+	// registered after the adapter is attached
+	const levelColored Level = 101
+	if err := RegisterLevel(levelColored, LevelSpec{Name: "colored", Short: "CLR", Severity: 33, Color: "<c>"}); err != nil {
+		t.Fatal(err)
+	}
+	l.Log(levelColored, "registered")
+
+	if got, want := out.String(), "<c>registered"+suffixReset+lineFeed; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
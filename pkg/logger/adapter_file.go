@@ -2,9 +2,13 @@ package logger
 
 import (
 	"bufio"
+	"crypto/cipher"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 )
 
 // FileAdapterConfig sturcture
@@ -15,9 +19,43 @@ type FileAdapterConfig struct {
 	Rotate    bool
 	AutoFlush bool
 	Format    string
+	Encoding  Encoding
 	MaxLength uint32
+	TimeZone  string // "" for local, "UTC" or IANA time zone name, also of Rotate
+	Filter    Filter // nil for all records above Level
+
+	MultiLine  MultiLine // how line breaks in a text record are written
+	LineMarker string    // prefix of continuation lines for MultiLineIndent
+
+	BufferSize    int           // 0 for default size (4KB)
+	FlushInterval time.Duration // flush buffer periodically if not 0
+
+	Durability   Durability
+	SyncLevel    Level         // for DurabilityLevel
+	SyncInterval time.Duration // for DurabilityPeriodic
+
+	// AES key of 16, 24 or 32 bytes to encrypt the file, nil for plain text.
+	// Records are written in frames of AES-GCM, see NewDecryptReader.
+	EncryptionKey []byte
 }
 
+// sealer rewrites lines of file adapter, e.g. to chain records.
+type sealer interface {
+	seal(b, line []byte) []byte // appends sealed line to b
+	commit()                    // the line last sealed is written
+	recover(name string) error  // the file is opened again after a failure
+}
+
+// Durability is fsync policy of file adapter.
+type Durability int
+
+// durability policies
+const (
+	DurabilityNone     Durability = iota // never fsync, rely on OS
+	DurabilityLevel                      // fsync after each record of SyncLevel and above
+	DurabilityPeriodic                   // flush and fsync every SyncInterval
+)
+
 // NewFileAdapterConfig returns a new FileAdapterConfig instance.
 func NewFileAdapterConfig() *FileAdapterConfig {
 	return &FileAdapterConfig{
@@ -26,7 +64,15 @@ func NewFileAdapterConfig() *FileAdapterConfig {
 		Rotate:    false,
 		AutoFlush: false,
 		Format:    DefaultFormat,
+		Encoding:  EncodingText,
 		MaxLength: 0,
+
+		MultiLine:  MultiLineKeep,
+		LineMarker: DefaultLineMarker,
+
+		Durability:   DurabilityNone,
+		SyncLevel:    LevelError,
+		SyncInterval: time.Second,
 	}
 }
 
@@ -36,13 +82,26 @@ func (c *FileAdapterConfig) id() AdapterID {
 
 ///////////////////////////////////////////////////////////////////////
 
+// interval of retrying to open the file after a failure
+const fileRetryInterval = time.Second
+
 type fileAdapter struct {
-	lock   sync.Mutex // file should be protected
-	last   string
-	file   *os.File
-	writer *bufio.Writer
-	config FileAdapterConfig
-	w      logWriter
+	lock    sync.Mutex     // file should be protected
+	last    string         // day of the current file
+	loc     *time.Location // time zone of the day
+	file    *os.File
+	writer  *bufio.Writer
+	config  FileAdapterConfig
+	enc     *encoder
+	sealer  sealer      // rewrites lines, nil for encoded lines as they are
+	aead    cipher.AEAD // encrypts frames, nil for plain text
+	lastErr error
+	retryAt time.Time
+
+	// background loop
+	stop    chan struct{}
+	done    sync.WaitGroup
+	syncErr error // last error of background loop, reported by flush()
 }
 
 func newFileAdapter() adapter {
@@ -59,27 +118,153 @@ func (a *fileAdapter) init(c AdapterConfig) error {
 		return ErrInvalidConfig
 	}
 
-	if cc.Level < LevelDebug || cc.Level > LevelFatal {
+	if !cc.Level.valid() {
 		return ErrInvalidLevel
 	}
 
+	if cc.BufferSize < 0 || cc.FlushInterval < 0 {
+		return ErrInvalidConfig
+	}
+
+	switch cc.Durability {
+	case DurabilityNone:
+	case DurabilityLevel:
+		if !cc.SyncLevel.valid() {
+			return ErrInvalidLevel
+		}
+	case DurabilityPeriodic:
+		if cc.SyncInterval <= 0 {
+			return ErrInvalidConfig
+		}
+	default:
+		return ErrInvalidConfig
+	}
+
+	enc, err := makeEncoder(writerConfig{
+		format:     cc.Format,
+		encoding:   cc.Encoding,
+		maxMsgLen:  cc.MaxLength,
+		timeZone:   cc.TimeZone,
+		multiLine:  cc.MultiLine,
+		lineMarker: cc.LineMarker,
+	})
+	if err != nil {
+		return err
+	}
+	loc, err := loadLocation(cc.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	var aead cipher.AEAD
+	if cc.EncryptionKey != nil {
+		if aead, err = newAEAD(cc.EncryptionKey); err != nil {
+			return ErrInvalidConfig
+		}
+	}
+
 	a.config = *cc // deep copy
-	a.w = makeWriter(cc.Format, cc.MaxLength, "", "")
-	if err := a.openFile(); err != nil {
+	a.enc = enc
+	a.loc = loc
+	a.aead = aead
+	if err := a.openFile(a.config.Truncate); err != nil {
 		return err
 	}
 
+	if a.config.FlushInterval > 0 || a.config.Durability == DurabilityPeriodic {
+		a.stop = make(chan struct{})
+		a.done.Add(1)
+		go a.background()
+	}
 	return nil
 }
 
-func (a *fileAdapter) rotateFile() {
-	a.closeFile()
+// newTicker returns ticks of a ticker and its stop function,
+// replaced by tests to tick the background loop.
+var newTicker = func(d time.Duration) (<-chan time.Time, func()) {
+	t := time.NewTicker(d)
+	return t.C, t.Stop
+}
+
+// background flushes buffer every FlushInterval
+// and flushes and fsyncs the file every SyncInterval.
+func (a *fileAdapter) background() {
+	defer a.done.Done()
+
+	var flushC, syncC <-chan time.Time
+	if a.config.FlushInterval > 0 {
+		c, stop := newTicker(a.config.FlushInterval)
+		defer stop()
+		flushC = c
+	}
+	if a.config.Durability == DurabilityPeriodic {
+		c, stop := newTicker(a.config.SyncInterval)
+		defer stop()
+		syncC = c
+	}
+
+	for {
+		var err error
+		select {
+		case <-flushC:
+			err = a.flushBuffer(false)
+		case <-syncC:
+			err = a.flushBuffer(true)
+		case <-a.stop:
+			return
+		}
+
+		if err != nil {
+			a.lock.Lock()
+			a.syncErr = err
+			a.lock.Unlock()
+		}
+	}
+}
+
+// flushBuffer flushes buffer with the lock and fsyncs the file without it.
+func (a *fileAdapter) flushBuffer(sync bool) error {
+	a.lock.Lock()
+	f := a.file
+	if a.writer != nil {
+		if err := a.writer.Flush(); err != nil {
+			err = a.fail(err)
+			a.lock.Unlock()
+			return err
+		}
+	}
+	a.lock.Unlock()
+
+	if !sync || f == nil {
+		return nil
+	}
+	return syncFile(f)
+}
+
+// syncFile fsyncs f, ignoring f closed by rotation meanwhile,
+// replaced by tests to see which records are synced.
+var syncFile = func(f *os.File) error {
+	if err := f.Sync(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+func (a *fileAdapter) rotateFile() error {
+	if err := a.closeFile(); err != nil {
+		return err
+	}
+
 	newName := a.config.Filename + "." + a.last
-	os.Rename(a.config.Filename, newName)
-	a.openFile()
+	if err := os.Rename(a.config.Filename, newName); err != nil && !os.IsNotExist(err) {
+		// the file is closed, the caller fails and ensureOpen opens
+		// the current file again, which keeps records of both days
+		return err
+	}
+	return a.openFile(a.config.Truncate)
 }
 
-func (a *fileAdapter) openFile() error {
+func (a *fileAdapter) openFile(truncate bool) error {
 	// path check and create
 	dir, _ := filepath.Split(a.config.Filename)
 	if len(dir) > 0 {
@@ -92,7 +277,11 @@ func (a *fileAdapter) openFile() error {
 	}
 
 	flags := os.O_WRONLY | os.O_CREATE
-	if a.config.Truncate {
+	if a.aead != nil {
+		// read to recover frames
+		flags = os.O_RDWR | os.O_CREATE
+	}
+	if truncate {
 		flags |= os.O_TRUNC
 	} else {
 		flags |= os.O_APPEND
@@ -103,63 +292,225 @@ func (a *fileAdapter) openFile() error {
 		return err
 	}
 
+	var w io.Writer = f
+	if a.aead != nil {
+		// a frame per flush of the buffer
+		fw, err := prepareEncrypted(f, a.aead)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		w = fw
+	}
+
 	a.file = f
-	a.writer = bufio.NewWriter(f)
+	a.writer = bufio.NewWriterSize(w, a.config.BufferSize)
 	return nil
 }
 
-func (a *fileAdapter) closeFile() {
+func (a *fileAdapter) closeFile() (err error) {
 	if a.writer != nil {
-		a.writer.Flush()
+		err = a.writer.Flush()
+	}
+	if a.file != nil && a.config.Durability != DurabilityNone && err == nil {
+		err = a.file.Sync()
 	}
 	if a.file != nil {
-		a.file.Close()
+		if cerr := a.file.Close(); err == nil {
+			err = cerr
+		}
 	}
 	a.writer = nil
 	a.file = nil
+	return
+}
+
+// fail closes the file after an error,
+// so that the file is opened again by a following write.
+func (a *fileAdapter) fail(err error) error {
+	a.closeFile()
+	a.lastErr = err
+	a.retryAt = time.Now().Add(fileRetryInterval)
+	return err
+}
+
+// ensureOpen opens the file again after a failure
+// at most once per fileRetryInterval.
+func (a *fileAdapter) ensureOpen() error {
+	if a.writer != nil {
+		return nil
+	}
+
+	if time.Now().Before(a.retryAt) {
+		return a.lastErr
+	}
+	if err := a.openFile(false); err != nil {
+		return a.fail(err)
+	}
+	if err := a.recoverSealer(); err != nil {
+		return a.fail(err)
+	}
+	return nil
+}
+
+// recoverSealer lets sealer recover from lines lost by a failure.
+func (a *fileAdapter) recoverSealer() error {
+	if a.sealer == nil {
+		return nil
+	}
+	return a.sealer.recover(a.config.Filename)
+}
+
+// reopen closes and opens the file again in append mode,
+// so that an external rotator can move the file away.
+func (a *fileAdapter) reopen() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.enc == nil {
+		// detached
+		return nil
+	}
+
+	failed := a.writer == nil
+	if err := a.closeFile(); err != nil {
+		return a.fail(err)
+	}
+	if err := a.openFile(false); err != nil {
+		return a.fail(err)
+	}
+	if failed {
+		if err := a.recoverSealer(); err != nil {
+			return a.fail(err)
+		}
+	}
+	return nil
 }
 
 func (a *fileAdapter) uninit() {
+	// stop background loop before taking the lock, the loop takes it too
+	if a.stop != nil {
+		close(a.stop)
+		a.done.Wait()
+		a.stop = nil
+	}
+
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
 	a.closeFile()
 	a.last = ""
-	a.w = nil
+	a.enc = nil
 }
 
-func (a *fileAdapter) write(msg *message) {
+func (a *fileAdapter) write(msg *message) (bool, error) {
+	f, wrote, err := a.writeRecord(msg)
+	if err != nil {
+		return wrote, err
+	}
+
+	// fsync without the lock
+	if f != nil {
+		return wrote, syncFile(f)
+	}
+	return wrote, nil
+}
+
+// writeRecord writes a record and returns the file to be fsynced by the record.
+func (a *fileAdapter) writeRecord(msg *message) (*os.File, bool, error) {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
-	if a.writer == nil {
-		return
+	if a.enc == nil {
+		// detached
+		return nil, false, nil
+	}
+
+	if !msg.Level.atLeast(a.config.Level) {
+		return nil, false, nil
 	}
 
-	if a.config.Level > msg.level {
-		return
+	if a.config.Filter != nil && !a.config.Filter.Match(&msg.Record) {
+		return nil, false, nil
 	}
 
 	if a.config.Rotate {
-		if dayNow := msg.time.Format("20060102"); dayNow != a.last {
+		if dayNow := msg.Time.In(a.loc).Format("20060102"); dayNow != a.last {
+			var err error
 			if len(a.last) > 0 {
-				a.rotateFile()
+				err = a.rotateFile()
 			}
 			a.last = dayNow
+			if err != nil {
+				return nil, false, a.fail(err)
+			}
+		}
+	}
+
+	if err := a.ensureOpen(); err != nil {
+		return nil, false, err
+	}
+
+	line := msg.encode(a.enc)
+	if len(line) > 0 {
+		if err := a.writeLine(line); err != nil {
+			return nil, false, a.fail(err)
+		}
+	}
+	wrote := len(line) > 0
+
+	sync := a.config.Durability == DurabilityLevel && msg.Level.atLeast(a.config.SyncLevel)
+	if a.config.AutoFlush || sync {
+		if err := a.writer.Flush(); err != nil {
+			return nil, false, a.fail(err)
 		}
 	}
 
-	a.w(a.writer, msg, nil, nil)
-	if a.config.AutoFlush {
-		a.writer.Flush()
+	if sync {
+		return a.file, wrote, nil
+	}
+	return nil, wrote, nil
+}
+
+// writeLine writes an encoded record, sealed by sealer if set.
+func (a *fileAdapter) writeLine(line []byte) error {
+	if a.sealer == nil {
+		_, err := a.writer.Write(line)
+		return err
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	buf.b = a.sealer.seal(buf.b, line)
+	if _, err := a.writer.Write(buf.b); err != nil {
+		return err
+	}
+	a.sealer.commit()
+	return nil
+}
+
+func (a *fileAdapter) level() Level {
+	return a.config.Level
+}
+
+func (a *fileAdapter) captures() capture {
+	if a.enc == nil {
+		return 0
 	}
+	return a.enc.caps
 }
 
-func (a *fileAdapter) flush() {
+func (a *fileAdapter) flush() error {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
 	if a.writer != nil {
-		a.writer.Flush()
+		if err := a.writer.Flush(); err != nil {
+			return a.fail(err)
+		}
 	}
+
+	err := a.syncErr
+	a.syncErr = nil
+	return err
 }
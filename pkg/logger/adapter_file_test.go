@@ -0,0 +1,311 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestFileAdapterReopen(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+
+	l := New("file", false)
+	c := NewFileAdapterConfig()
+	c.Filename = filename
+	c.Format = "$msg"
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Detach(AdapterFile)
+
+	l.Information("before")
+	if err := os.Rename(filename, filename+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	l.Information("after")
+	l.Flush()
+
+	if got := readFile(t, filename+".1"); got != "before"+lineFeed {
+		t.Errorf("moved file: got %q", got)
+	}
+	if got := readFile(t, filename); got != "after"+lineFeed {
+		t.Errorf("reopened file: got %q", got)
+	}
+}
+
+func TestFileAdapterRotateTimeZone(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+
+	l := New("file", false)
+	c := NewFileAdapterConfig()
+	c.Filename = filename
+	c.Format = "$msg"
+	c.Rotate = true
+	c.TimeZone = "Asia/Tokyo"
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Detach(AdapterFile)
+
+	a := l.loadAdapters()[0].(*fileAdapter)
+	for _, r := range []struct {
+		msg string
+		at  time.Time
+	}{
+		{"first", time.Date(2021, 3, 4, 16, 0, 0, 0, time.UTC)},  // 03-05 01:00 in Tokyo
+		{"second", time.Date(2021, 3, 4, 23, 0, 0, 0, time.UTC)}, // 03-05 08:00
+		{"third", time.Date(2021, 3, 5, 15, 0, 0, 0, time.UTC)},  // 03-06 00:00
+	} {
+		msg := testMessage()
+		msg.Msg, msg.Time = r.msg, r.at
+		if _, err := a.write(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Flush()
+
+	if got := readFile(t, filename+".20210305"); got != "first"+lineFeed+"second"+lineFeed {
+		t.Errorf("rotated file: got %q", got)
+	}
+	if got := readFile(t, filename); got != "third"+lineFeed {
+		t.Errorf("current file: got %q", got)
+	}
+}
+
+func TestFileAdapterFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	filename := filepath.Join(dir, "app.log")
+
+	var fallback bytes.Buffer
+	var failures []AdapterID
+
+	l := New("file", false)
+	c := NewFileAdapterConfig()
+	c.Filename = filename
+	c.Format = "$msg"
+	c.AutoFlush = true
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Detach(AdapterFile)
+
+	fc := NewConsoleAdapterConfig()
+	fc.Format = "fallback $msg"
+	fc.Color = false
+	fc.Writer = &fallback
+	if err := l.SetFallback(fc); err != nil {
+		t.Fatal(err)
+	}
+	l.SetErrorHandler(func(id AdapterID, err error) {
+		failures = append(failures, id)
+	})
+
+	// a file in place of the directory, the file can't be reopened
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Reopen(); err == nil {
+		t.Fatal("reopen: got no error")
+	}
+
+	l.Information("lost")
+	l.Information("throttled")
+	if got, want := fallback.String(), "fallback lost"+lineFeed+"fallback throttled"+lineFeed; got != want {
+		t.Errorf("fallback: got %q, want %q", got, want)
+	}
+	if len(failures) != 2 || failures[0] != AdapterFile {
+		t.Errorf("unexpected failures %v", failures)
+	}
+
+	if err := os.Remove(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	l.Information("recovered")
+	if got := readFile(t, filename); got != "recovered"+lineFeed {
+		t.Errorf("file: got %q", got)
+	}
+}
+
+func TestReopenOnSignalError(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no SIGHUP")
+	}
+	dir := filepath.Join(t.TempDir(), "logs")
+
+	l := New("file", false)
+	c := NewFileAdapterConfig()
+	c.Filename = filepath.Join(dir, "app.log")
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Detach(AdapterFile)
+
+	failures := make(chan AdapterID, 1)
+	l.SetErrorHandler(func(id AdapterID, err error) {
+		failures <- id
+	})
+	stop := l.ReopenOnSignal()
+	defer stop()
+
+	// a file in place of the directory, the file can't be reopened
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case id := <-failures:
+		if id != AdapterFile {
+			t.Errorf("got failure of %v", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error reported")
+	}
+}
+
+// fakeTickers replaces tickers of background loops by channels of the intervals.
+func fakeTickers(t *testing.T, intervals ...time.Duration) map[time.Duration]chan time.Time {
+	ticks := make(map[time.Duration]chan time.Time)
+	for _, d := range intervals {
+		ticks[d] = make(chan time.Time)
+	}
+	old := newTicker
+	newTicker = func(d time.Duration) (<-chan time.Time, func()) {
+		return ticks[d], func() {}
+	}
+	t.Cleanup(func() { newTicker = old })
+	return ticks
+}
+
+// tick ticks twice, the second tick is received after the first is handled.
+func tick(c chan time.Time) {
+	c <- time.Now()
+	c <- time.Now()
+}
+
+func TestFileAdapterPeriodicSync(t *testing.T) {
+	ticks := fakeTickers(t, 10*time.Millisecond)
+	filename := filepath.Join(t.TempDir(), "app.log")
+
+	l := New("file", false)
+	c := NewFileAdapterConfig()
+	c.Filename = filename
+	c.Format = "$msg"
+	c.Durability = DurabilityPeriodic
+	c.SyncInterval = 10 * time.Millisecond
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Detach(AdapterFile)
+
+	l.Information("synced")
+	tick(ticks[c.SyncInterval])
+
+	if got := readFile(t, filename); got != "synced"+lineFeed {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestFileAdapterLevelSync(t *testing.T) {
+	var synced []string
+	old := syncFile
+	syncFile = func(f *os.File) error {
+		synced = append(synced, f.Name())
+		return nil
+	}
+	t.Cleanup(func() { syncFile = old })
+	filename := filepath.Join(t.TempDir(), "app.log")
+
+	l := New("file", false)
+	c := NewFileAdapterConfig()
+	c.Filename = filename
+	c.Format = "$msg"
+	c.BufferSize = 64 * 1024
+	c.Durability = DurabilityLevel
+	c.SyncLevel = LevelError
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Detach(AdapterFile)
+
+	l.Warning("buffered")
+	if len(synced) != 0 {
+		t.Errorf("below SyncLevel: got %d syncs", len(synced))
+	}
+	if got := readFile(t, filename); got != "" {
+		t.Errorf("below SyncLevel: got %q", got)
+	}
+
+	l.Error("synced")
+	l.Log(LevelFatal, "synced too")
+	if len(synced) != 2 || synced[0] != filename {
+		t.Errorf("at and above SyncLevel: got syncs of %v", synced)
+	}
+	if got, want := readFile(t, filename), "buffered"+lineFeed+"synced"+lineFeed+"synced too"+lineFeed; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFileAdapterFlushInterval(t *testing.T) {
+	ticks := fakeTickers(t, 10*time.Millisecond)
+	filename := filepath.Join(t.TempDir(), "app.log")
+
+	l := New("file", false)
+	c := NewFileAdapterConfig()
+	c.Filename = filename
+	c.Format = "$msg"
+	c.BufferSize = 64 * 1024
+	c.FlushInterval = 10 * time.Millisecond
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Information("buffered")
+	if got := readFile(t, filename); got != "" {
+		t.Errorf("before flush: got %q", got)
+	}
+	tick(ticks[c.FlushInterval])
+	if got := readFile(t, filename); got != "buffered"+lineFeed {
+		t.Errorf("after flush: got %q", got)
+	}
+
+	// the loop is stopped, nothing receives ticks
+	l.Detach(AdapterFile)
+	select {
+	case ticks[c.FlushInterval] <- time.Now():
+		t.Error("background loop not stopped")
+	default:
+	}
+}
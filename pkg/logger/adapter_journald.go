@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ErrUnsupported is returned when an adapter isn't supported on the platform.
+var ErrUnsupported = errors.New("not supported on this platform")
+
+// DefaultJournaldSocket is the socket of systemd-journald native protocol.
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldAdapterConfig structure
+// A journald adapter sends records to systemd-journald by its native protocol,
+// on linux only. MESSAGE is formatted by Format, and the record is sent with
+//
+//	PRIORITY, SYSLOG_IDENTIFIER, LOGGER_NAME, CODE_FILE, CODE_LINE, CODE_FUNC
+//
+// and its fields, whose keys are converted to journal field names:
+// upper case, characters other than A-Z, 0-9 and '_' replaced by '_',
+// and prefixed by "F_" unless starting with a letter or when the name is
+// one of the fields above, so that fields can't override them.
+// Records too large for a datagram are passed in a sealed memfd.
+type JournaldAdapterConfig struct {
+	Level      Level
+	Format     string // MESSAGE, "$msg" by default
+	MaxLength  uint32
+	Filter     Filter // nil for all records above Level
+	Identifier string // SYSLOG_IDENTIFIER, executable name if empty
+	Socket     string // DefaultJournaldSocket if empty
+}
+
+// NewJournaldAdapterConfig returns a new JournaldAdapterConfig instance.
+func NewJournaldAdapterConfig() *JournaldAdapterConfig {
+	return &JournaldAdapterConfig{
+		Level:  LevelDebug,
+		Format: "$msg",
+		Socket: DefaultJournaldSocket,
+	}
+}
+
+func (c *JournaldAdapterConfig) id() AdapterID {
+	return AdapterJournald
+}
+
+///////////////////////////////////////////////////////////////////////
+
+// maximum length of journal field names
+const journalNameMax = 64
+
+// journal fields written by the adapter, prefixed in record fields
+var journalReserved = map[string]bool{
+	"MESSAGE":           true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+	"LOGGER_NAME":       true,
+	"CODE_FILE":         true,
+	"CODE_LINE":         true,
+	"CODE_FUNC":         true,
+}
+
+// journalPriority maps a level to syslog priority by its severity.
+func journalPriority(l Level) int {
+	switch {
+	case l.atLeast(LevelPanic):
+		return 2 // crit
+	case l.atLeast(LevelError):
+		return 3 // err
+	case l.atLeast(LevelWarning):
+		return 4 // warning
+	case l.atLeast(LevelNotice):
+		return 5 // notice
+	case l.atLeast(LevelInformation):
+		return 6 // info
+	}
+	return 7 // debug
+}
+
+// journalFieldName converts a field key to a journal field name.
+func journalFieldName(key string) string {
+	b := make([]byte, 0, len(key)+2)
+	if len(key) == 0 || !isUpperAlpha(upper(key[0])) {
+		b = append(b, "F_"...)
+	}
+	for i := 0; i < len(key) && len(b) < journalNameMax; i++ {
+		c := upper(key[i])
+		if !isUpperAlpha(c) && (c < '0' || c > '9') {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	if journalReserved[string(b)] {
+		b = append([]byte("F_"), b...)
+	}
+	return string(b)
+}
+
+func upper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+func isUpperAlpha(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+// appendJournalField appends a field in native protocol.
+// Values with line feeds are written in binary form:
+//
+//	NAME\n<64-bit little endian length>VALUE\n
+func appendJournalField(b []byte, name string, value []byte) []byte {
+	b = append(b, name...)
+	for _, c := range value {
+		if c == '\n' {
+			b = append(b, '\n')
+			b = append(b, make([]byte, 8)...)
+			binary.LittleEndian.PutUint64(b[len(b)-8:], uint64(len(value)))
+			b = append(b, value...)
+			return append(b, '\n')
+		}
+	}
+	b = append(b, '=')
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+// appendJournalRecord appends a record in native protocol with its MESSAGE.
+func appendJournalRecord(b []byte, msg *message, text []byte, ident string) []byte {
+	b = appendJournalField(b, "MESSAGE", text)
+	b = appendJournalField(b, "PRIORITY", strconv.AppendInt(nil, int64(journalPriority(msg.Level)), 10))
+	if len(ident) > 0 {
+		b = appendJournalField(b, "SYSLOG_IDENTIFIER", []byte(ident))
+	}
+	if len(msg.Name) > 0 {
+		b = appendJournalField(b, "LOGGER_NAME", []byte(msg.Name))
+	}
+	if len(msg.path) > 0 {
+		b = appendJournalField(b, "CODE_FILE", []byte(msg.path))
+		b = appendJournalField(b, "CODE_LINE", strconv.AppendInt(nil, int64(msg.Line), 10))
+	}
+	if len(msg.Function) > 0 {
+		b = appendJournalField(b, "CODE_FUNC", []byte(msg.Function))
+	}
+
+	var value []byte
+	for _, f := range msg.Fields {
+		value = appendValue(value[:0], f.Value)
+		b = appendJournalField(b, journalFieldName(f.Key), value)
+	}
+	return b
+}
+
+// defaultIdentifier is the executable name.
+func defaultIdentifier() string {
+	return filepath.Base(os.Args[0])
+}
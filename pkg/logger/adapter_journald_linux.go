@@ -0,0 +1,233 @@
+//go:build linux
+// +build linux
+
+package logger
+
+import (
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+type journaldAdapter struct {
+	lock   sync.Mutex
+	conn   *net.UnixConn
+	addr   *net.UnixAddr
+	config JournaldAdapterConfig
+	enc    *encoder
+	ident  string
+	buf    []byte
+}
+
+func newJournaldAdapter() adapter {
+	return &journaldAdapter{}
+}
+
+func (a *journaldAdapter) id() AdapterID {
+	return AdapterJournald
+}
+
+func (a *journaldAdapter) init(c AdapterConfig) error {
+	cc, ok := c.(*JournaldAdapterConfig)
+	if !ok {
+		return ErrInvalidConfig
+	}
+
+	if !cc.Level.valid() {
+		return ErrInvalidLevel
+	}
+
+	enc, err := makeEncoder(writerConfig{
+		format:     cc.Format,
+		encoding:   EncodingText,
+		maxMsgLen:  cc.MaxLength,
+		multiLine:  MultiLineKeep,
+		lineMarker: DefaultLineMarker,
+	})
+	if err != nil {
+		return err
+	}
+
+	socket := cc.Socket
+	if len(socket) == 0 {
+		socket = DefaultJournaldSocket
+	}
+	if _, err := os.Stat(socket); err != nil {
+		return err
+	}
+	// unbound, autobound by the kernel
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+
+	a.conn = conn
+	a.addr = &net.UnixAddr{Name: socket, Net: "unixgram"}
+
+	a.ident = cc.Identifier
+	if len(a.ident) == 0 {
+		a.ident = defaultIdentifier()
+	}
+	a.config = *cc // deep copy
+	a.enc = enc
+	return nil
+}
+
+func (a *journaldAdapter) uninit() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.conn != nil {
+		a.conn.Close()
+		a.conn = nil
+	}
+	a.enc = nil
+}
+
+func (a *journaldAdapter) write(msg *message) (bool, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.enc == nil {
+		// detached
+		return false, nil
+	}
+
+	if !msg.Level.atLeast(a.config.Level) {
+		return false, nil
+	}
+
+	if a.config.Filter != nil && !a.config.Filter.Match(&msg.Record) {
+		return false, nil
+	}
+
+	text := msg.encode(a.enc)
+	if len(text) == 0 {
+		return false, nil
+	}
+	text = text[:len(text)-len(lineFeed)]
+
+	a.buf = appendJournalRecord(a.buf[:0], msg, text, a.ident)
+	err := a.send(a.buf)
+	return err == nil, err
+}
+
+// send sends a datagram, or a memfd of it when it's too large.
+// The socket isn't connected, so that journald can be restarted.
+func (a *journaldAdapter) send(b []byte) error {
+	_, _, err := a.conn.WriteMsgUnix(b, nil, a.addr)
+	if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+		return a.sendFile(b)
+	}
+	return err
+}
+
+// sendFile passes b in a sealed memfd, or an unlinked file in /dev/shm
+// where memfd isn't available.
+func (a *journaldAdapter) sendFile(b []byte) error {
+	f, err := memfd(b)
+	if err != nil {
+		if f, err = shmFile(b); err != nil {
+			return err
+		}
+	}
+	defer f.Close()
+
+	_, _, err = a.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), a.addr)
+	return err
+}
+
+func (a *journaldAdapter) level() Level {
+	return a.config.Level
+}
+
+func (a *journaldAdapter) captures() capture {
+	if a.enc == nil {
+		return 0
+	}
+	return a.enc.caps
+}
+
+func (a *journaldAdapter) flush() error {
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////
+
+// memfd_create is missing in syscall package. The numbers are from syscall
+// tables of Linux: include/uapi/asm-generic/unistd.h for arm64, loong64 and
+// riscv64, and arch/*/entry/syscalls or arch/*/kernel/syscalls for the others.
+// Other architectures use a file in /dev/shm instead.
+var sysMemfdCreate = map[string]uintptr{
+	"386":      356,
+	"amd64":    319,
+	"arm":      385,
+	"arm64":    279,
+	"loong64":  279,
+	"riscv64":  279,
+	"ppc64":    360,
+	"ppc64le":  360,
+	"s390x":    350,
+	"mips":     4354,
+	"mipsle":   4354,
+	"mips64":   5314,
+	"mips64le": 5314,
+}
+
+const (
+	mfdCloexec       = 0x1
+	mfdAllowSealing  = 0x2
+	fAddSeals        = 1033
+	fGetSeals        = 1034
+	fSealSeal        = 0x1
+	fSealShrink      = 0x2
+	fSealGrow        = 0x4
+	fSealWrite       = 0x8
+	journalMemfdName = "logger-journal"
+)
+
+// memfd returns a memfd of b, sealed as journald requires.
+func memfd(b []byte) (*os.File, error) {
+	nr, ok := sysMemfdCreate[runtime.GOARCH]
+	if !ok {
+		return nil, syscall.ENOSYS
+	}
+	name, err := syscall.BytePtrFromString(journalMemfdName)
+	if err != nil {
+		return nil, err
+	}
+	fd, _, errno := syscall.Syscall(nr, uintptr(unsafe.Pointer(name)), mfdCloexec|mfdAllowSealing, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	f := os.NewFile(fd, journalMemfdName)
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return nil, err
+	}
+	seals := fSealSeal | fSealShrink | fSealGrow | fSealWrite
+	if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, fAddSeals, uintptr(seals)); errno != 0 {
+		f.Close()
+		return nil, errno
+	}
+	return f, nil
+}
+
+// shmFile returns an unlinked temporary file of b in /dev/shm.
+func shmFile(b []byte) (*os.File, error) {
+	f, err := os.CreateTemp("/dev/shm", "logger-journal-")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// parseJournal parses native protocol fields.
+func parseJournal(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+	fields := map[string]string{}
+	for len(b) > 0 {
+		i := bytes.IndexAny(b, "=\n")
+		if i < 0 {
+			t.Fatalf("no value: %q", b)
+		}
+		name := string(b[:i])
+		if b[i] == '=' {
+			j := bytes.IndexByte(b, '\n')
+			fields[name] = string(b[i+1 : j])
+			b = b[j+1:]
+			continue
+		}
+		n := int(binary.LittleEndian.Uint64(b[i+1:]))
+		fields[name] = string(b[i+9 : i+9+n])
+		b = b[i+9+n+1:]
+	}
+	return fields
+}
+
+// receiveJournal reads a datagram or the file passed in it.
+func receiveJournal(t *testing.T, ln *net.UnixConn) []byte {
+	t.Helper()
+	buf := make([]byte, 64*1024)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err := ln.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oobn == 0 {
+		return buf[:n]
+	}
+
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "journal")
+	defer f.Close()
+	if seals, _, errno := syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), fGetSeals, 0); errno == 0 && seals&fSealWrite == 0 {
+		t.Errorf("memfd not sealed: %#x", seals)
+	}
+	// the offset is shared with the sender, journald reads it by pread or mmap
+	b, err := io.ReadAll(io.NewSectionReader(f, 0, 1<<30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestJournaldAdapter(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "socket")
+	ln, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	l := New("app", false)
+	c := NewJournaldAdapterConfig()
+	c.Socket = socket
+	c.Identifier = "test"
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Detach(AdapterJournald)
+
+	l.Warning("multi\nline", F("user id", 7), F("_private", "x"), F("priority", "high"))
+	fields := parseJournal(t, receiveJournal(t, ln))
+	for name, want := range map[string]string{
+		"MESSAGE":           "multi\nline",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "test",
+		"LOGGER_NAME":       "app",
+		"USER_ID":           "7",
+		"F__PRIVATE":        "x",
+		"F_PRIORITY":        "high",
+	} {
+		if fields[name] != want {
+			t.Errorf("%s: got %q, want %q", name, fields[name], want)
+		}
+	}
+	if !strings.HasSuffix(fields["CODE_FILE"], "adapter_journald_linux_test.go") ||
+		!strings.HasSuffix(fields["CODE_FUNC"], "TestJournaldAdapter") || fields["CODE_LINE"] == "" {
+		t.Errorf("caller: got %q:%q %q", fields["CODE_FILE"], fields["CODE_LINE"], fields["CODE_FUNC"])
+	}
+
+	// larger than a datagram can be
+	large := strings.Repeat("x", 4<<20)
+	l.Error(large)
+	fields = parseJournal(t, receiveJournal(t, ln))
+	if fields["MESSAGE"] != large || fields["PRIORITY"] != "3" {
+		t.Errorf("large: got %d bytes, priority %q", len(fields["MESSAGE"]), fields["PRIORITY"])
+	}
+}
+
+func TestJournalFieldName(t *testing.T) {
+	for key, want := range map[string]string{
+		"user":              "USER",
+		"req-id":            "REQ_ID",
+		"2fa":               "F_2FA",
+		"":                  "F_",
+		"_source":           "F__SOURCE",
+		"Ünicode":           "F___NICODE",
+		"long_key":          "LONG_KEY",
+		"message":           "F_MESSAGE",
+		"Priority":          "F_PRIORITY",
+		"code_file":         "F_CODE_FILE",
+		"syslog_identifier": "F_SYSLOG_IDENTIFIER",
+		"message_id":        "MESSAGE_ID",
+	} {
+		if got := journalFieldName(key); got != want {
+			t.Errorf("%q: got %q, want %q", key, got, want)
+		}
+	}
+	if got := journalFieldName(strings.Repeat("a", 100)); len(got) != journalNameMax {
+		t.Errorf("long: got %d", len(got))
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !linux
+// +build !linux
+
+package logger
+
+// journaldAdapter fails to attach where journald doesn't exist.
+type journaldAdapter struct{}
+
+func newJournaldAdapter() adapter {
+	return &journaldAdapter{}
+}
+
+func (a *journaldAdapter) id() AdapterID {
+	return AdapterJournald
+}
+
+func (a *journaldAdapter) init(c AdapterConfig) error {
+	return ErrUnsupported
+}
+
+func (a *journaldAdapter) uninit() {}
+
+func (a *journaldAdapter) write(msg *message) (bool, error) {
+	return false, nil
+}
+
+func (a *journaldAdapter) level() Level {
+	return LevelDebug
+}
+
+func (a *journaldAdapter) captures() capture {
+	return 0
+}
+
+func (a *journaldAdapter) flush() error {
+	return nil
+}
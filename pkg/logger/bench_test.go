@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// Results before records were encoded into pooled buffers,
+// when every adapter formatted the record by fmt.Fprintf:
+//
+//	BenchmarkLoggerText             560 B/op   13 allocs/op
+//	BenchmarkLoggerJSON            1312 B/op   36 allocs/op
+//	BenchmarkLoggerTextTwoAdapters  808 B/op   23 allocs/op
+//	BenchmarkLoggerJSONTwoAdapters 2312 B/op   69 allocs/op
+//
+// Now each of them is 64 B/op, 1 allocs/op, the fields of the call.
+
+func attachBenchAdapters(tb testing.TB, l *Logger, encoding Encoding, withFile bool) {
+	c := NewConsoleAdapterConfig()
+	c.Color = false
+	c.Encoding = encoding
+	c.Writer = io.Discard
+	if err := l.Attach(c); err != nil {
+		tb.Fatal(err)
+	}
+
+	if withFile {
+		fc := NewFileAdapterConfig()
+		fc.Filename = filepath.Join(tb.TempDir(), "bench.log")
+		fc.Encoding = encoding
+		if err := l.Attach(fc); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func benchmarkLogger(b *testing.B, encoding Encoding, withFile bool) {
+	l := New("bench", false)
+	attachBenchAdapters(b, l, encoding, withFile)
+	defer l.Detach(AdapterFile)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Information("request handled", F("status", 200), F("path", "/api/v1/users"))
+	}
+}
+
+func BenchmarkLoggerText(b *testing.B)            { benchmarkLogger(b, EncodingText, false) }
+func BenchmarkLoggerJSON(b *testing.B)            { benchmarkLogger(b, EncodingJSON, false) }
+func BenchmarkLoggerTextTwoAdapters(b *testing.B) { benchmarkLogger(b, EncodingText, true) }
+func BenchmarkLoggerJSONTwoAdapters(b *testing.B) { benchmarkLogger(b, EncodingJSON, true) }
+
+// TestLoggerAllocs keeps the encoding path allocation free:
+// only the fields slice of the call is allocated, whatever the
+// encoding and number of adapters, where fmt.Fprintf made 13 to 69.
+func TestLoggerAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations aren't stable with -race")
+	}
+
+	for _, encoding := range []Encoding{EncodingText, EncodingJSON} {
+		l := New("bench", false)
+		attachBenchAdapters(t, l, encoding, true)
+
+		allocs := testing.AllocsPerRun(100, func() {
+			l.Information("request handled", F("status", 200), F("path", "/api/v1/users"))
+		})
+		l.Detach(AdapterFile)
+		if allocs > 1 {
+			t.Errorf("encoding %v: got %v allocs, want at most 1", encoding, allocs)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package logger
+
+import "sync"
+
+// buffer is a reusable byte slice for encoding records.
+type buffer struct {
+	b []byte
+}
+
+// Write makes buffer an io.Writer for fmt.
+func (buf *buffer) Write(p []byte) (int, error) {
+	buf.b = append(buf.b, p...)
+	return len(p), nil
+}
+
+// buffers grown beyond this are not reused to keep the pool small
+const maxPooledBuffer = 64 * 1024
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &buffer{b: make([]byte, 0, 512)}
+	},
+}
+
+func getBuffer() *buffer {
+	buf := bufferPool.Get().(*buffer)
+	buf.b = buf.b[:0]
+	return buf
+}
+
+func putBuffer(buf *buffer) {
+	if cap(buf.b) <= maxPooledBuffer {
+		bufferPool.Put(buf)
+	}
+}
+
+// encoded is a record rendered by an encoder.
+type encoded struct {
+	enc *encoder
+	buf *buffer
+}
+
+// encode renders the record once per encoder,
+// adapters sharing an encoder reuse the output.
+// The output is valid until the record is released.
+func (m *message) encode(enc *encoder) []byte {
+	for _, e := range m.encoded {
+		if e.enc == enc {
+			return e.buf.b
+		}
+	}
+
+	buf := getBuffer()
+	buf.b = enc.encode(buf.b, m)
+	m.encoded = append(m.encoded, encoded{enc: enc, buf: buf})
+	return buf.b
+}
+
+// release returns encoded outputs to the pool.
+func (m *message) release() {
+	for i, e := range m.encoded {
+		putBuffer(e.buf)
+		m.encoded[i] = encoded{}
+	}
+	m.encoded = m.encoded[:0]
+}
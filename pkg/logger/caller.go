@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// WithCallerSkip for singleton
+func WithCallerSkip(n int) *Logger { return lgr.WithCallerSkip(n) }
+
+// WithCallerSkip returns a child logger of the same name
+// which reports the caller n more frames up the stack,
+// e.g. 1 for records logged by a wrapper function.
+// Skips of children are added up.
+func (logger *Logger) WithCallerSkip(n int) *Logger {
+	return &Logger{
+		core:       logger.core,
+		name:       logger.name,
+		fields:     logger.fields,
+		callerSkip: logger.callerSkip + n,
+	}
+}
+
+// SetCallerLookup for singleton
+func SetCallerLookup(enable bool) { lgr.SetCallerLookup(enable) }
+
+// SetCallerLookup enables or disables looking up the caller of logging functions.
+// When disabled, Function, File and Line of records are empty,
+// which saves the lookup on every record.
+func (logger *Logger) SetCallerLookup(enable bool) {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+	logger.updateSettings(func(s *settings) { s.noCaller = !enable })
+}
+
+var (
+	helperLock sync.Mutex
+	helpers    atomic.Value // map[string]bool of function names, copied on write
+)
+
+func loadHelpers() map[string]bool {
+	h, _ := helpers.Load().(map[string]bool)
+	return h
+}
+
+// Helper marks the calling function as a logging helper.
+// Records logged in a helper, directly or through other helpers,
+// report the caller of the helper, like testing.T.Helper.
+// Helper applies to all loggers.
+func Helper() {
+	var pcs [1]uintptr
+	if runtime.Callers(2, pcs[:]) == 0 {
+		return
+	}
+
+	function := frameOf(pcs[0]).function
+	if loadHelpers()[function] {
+		return
+	}
+
+	helperLock.Lock()
+	defer helperLock.Unlock()
+
+	old := loadHelpers()
+	h := make(map[string]bool, len(old)+1)
+	for k := range old {
+		h[k] = true
+	}
+	h[function] = true
+	helpers.Store(h)
+}
+
+///////////////////////////////////////////////////////////////////////
+
+type callerInfo struct {
+	function string
+	file     string
+	line     int
+}
+
+// callerCache isn't bounded, it has an entry per call site
+// of logging functions and helpers, which are limited by the code.
+var (
+	callerLock  sync.RWMutex
+	callerCache = map[uintptr]callerInfo{} // by program counter of call site
+)
+
+// callerOf returns function, file and line of a caller,
+// skipping frames of helper functions.
+func callerOf(skip int) (function, file string, line int) {
+	h := loadHelpers()
+	var pcs [1]uintptr
+	for {
+		if runtime.Callers(skip+1, pcs[:]) == 0 {
+			return "null", "null", 0
+		}
+		c := frameOf(pcs[0])
+		if !h[c.function] {
+			return c.function, c.file, c.line
+		}
+		skip++
+	}
+}
+
+// frameOf returns the frame of a program counter.
+// Call sites are resolved once, resolving allocates.
+func frameOf(pc uintptr) callerInfo {
+	callerLock.RLock()
+	c, ok := callerCache[pc]
+	callerLock.RUnlock()
+	if ok {
+		return c
+	}
+
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	c = callerInfo{function: f.Function, file: f.File, line: f.Line}
+	callerLock.Lock()
+	callerCache[pc] = c
+	callerLock.Unlock()
+	return c
+}
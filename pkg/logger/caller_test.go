@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func newCallerLogger(t *testing.T, out *bytes.Buffer) *Logger {
+	t.Helper()
+	l := New("caller", false)
+	c := NewConsoleAdapterConfig()
+	c.Color = false
+	c.Format = "$function:$line"
+	c.Writer = out
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func logThroughWrapper(l *Logger) {
+	l.WithCallerSkip(1).Information("wrapped")
+}
+
+func logThroughHelper(l *Logger) {
+	Helper()
+	l.Information("helped")
+}
+
+func logThroughNestedHelper(l *Logger) {
+	Helper()
+	logThroughHelper(l)
+}
+
+func thisLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+func TestCallerSkip(t *testing.T) {
+	var out bytes.Buffer
+	l := newCallerLogger(t, &out)
+
+	line := thisLine()
+	logThroughWrapper(l)
+	logThroughHelper(l)
+	logThroughNestedHelper(l)
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), lineFeed), lineFeed)
+	if len(lines) != 3 {
+		t.Fatalf("got %q", out.String())
+	}
+	for i, got := range lines {
+		want := fmt.Sprintf("/pkg/logger.TestCallerSkip:%d", line+1+i)
+		if !strings.HasSuffix(got, want) {
+			t.Errorf("record %d: got %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestCallerLookupDisabled(t *testing.T) {
+	var out bytes.Buffer
+	l := newCallerLogger(t, &out)
+	l.SetCallerLookup(false)
+
+	l.Information("no caller")
+	if got := out.String(); got != ":0"+lineFeed {
+		t.Errorf("got %q", got)
+	}
+}
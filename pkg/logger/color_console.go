@@ -1,5 +1,14 @@
 package logger
 
+import (
+	"io"
+	"os"
+	"runtime"
+)
+
+// ColorReset is ANSI escape sequence which resets colors of palette.
+const ColorReset = suffixReset
+
 const (
 	suffixReset = "\033[0m"
 
@@ -36,4 +45,39 @@ const (
 	prefixLYellow = "\033[0;93m"
 	prefixBRed    = "\033[1;31m"
 	prefixBPurble = "\033[1;35m"
+	prefixDGray   = "\033[0;90m"
+	prefixLBlue   = "\033[0;94m"
 )
+
+// DefaultPalette returns console colors of all known levels.
+func DefaultPalette() map[Level]string {
+	specs := levelSpecs()
+	palette := make(map[Level]string, len(specs))
+	for l, spec := range specs {
+		palette[l] = spec.Color
+	}
+	return palette
+}
+
+// ColorSupported reports whether ANSI colors can be written to f.
+// NO_COLOR disables and FORCE_COLOR enables colors regardless of f.
+func ColorSupported(f *os.File) bool {
+	return colorSupported(f)
+}
+
+// colorSupported reports whether ANSI colors can be written to w,
+// which is a terminal only if it's an *os.File.
+func colorSupported(w io.Writer) bool {
+	if v, ok := os.LookupEnv("FORCE_COLOR"); ok && v != "0" && v != "false" {
+		return true
+	}
+	if v := os.Getenv("NO_COLOR"); len(v) > 0 {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// ANSI color code is not supported in windows' default terminal
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && f != nil && isTerminal(f)
+}
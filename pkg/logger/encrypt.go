@@ -0,0 +1,346 @@
+package logger
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Encrypted log files
+// A file adapter with EncryptionKey writes a file header:
+//
+//	<magic> <16 bytes random file ID> <12 bytes nonce> <AES-GCM tag of magic and file ID>
+//
+// which checks the key, followed by frames:
+//
+//	<4 bytes big endian size> <12 bytes nonce> <AES-GCM sealed lines>
+//
+// where size counts nonce and sealed lines. The size, the file ID and
+// the sequence number of the frame in the file are authenticated as
+// additional data, so that frames can't be dropped, reordered, duplicated
+// or moved to another file, except frames cut off at the end.
+//
+// A frame is written on each flush of the buffer, so a crash loses
+// at most the frames being written. When the file is opened again,
+// frames are authenticated and the file is cut at the first frame which
+// doesn't authenticate, e.g. a partial frame or zeros left by a crash.
+// The rest of a file corrupted other than at the end is left as it is
+// and opening it fails.
+
+// ErrInvalidFrame is returned when a frame of an encrypted log is corrupted
+// or can't be decrypted with the key.
+var ErrInvalidFrame = errors.New("invalid encrypted frame")
+
+// ErrTruncatedFrame is returned when an encrypted log ends in a frame,
+// e.g. after a crash while writing.
+var ErrTruncatedFrame = errors.New("truncated encrypted frame")
+
+const (
+	encryptMagic     = "LOGGCM1\n"
+	encryptIDSize    = 16
+	encryptNonceSize = 12
+	encryptTagSize   = 16
+	encryptHeadSize  = len(encryptMagic) + encryptIDSize + encryptNonceSize + encryptTagSize
+	frameHeaderSize  = 4
+	maxFramePlain    = 1 << 20 // larger writes are split into frames
+	maxFrameSize     = encryptNonceSize + maxFramePlain + encryptTagSize
+)
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// frameAD returns additional data of a frame.
+func frameAD(b []byte, size uint32, id []byte, seq uint64) []byte {
+	b = append(b[:0], 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b, size)
+	b = append(b, id...)
+	b = append(b, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(b[len(b)-8:], seq)
+	return b
+}
+
+// newEncryptHeader returns a file header with a new file ID.
+func newEncryptHeader(aead cipher.AEAD) ([]byte, error) {
+	b := make([]byte, len(encryptMagic)+encryptIDSize+encryptNonceSize, encryptHeadSize)
+	copy(b, encryptMagic)
+	if _, err := rand.Read(b[len(encryptMagic):]); err != nil {
+		return nil, err
+	}
+	nonce := b[len(encryptMagic)+encryptIDSize:]
+	return aead.Seal(b, nonce, nil, b[:len(encryptMagic)+encryptIDSize]), nil
+}
+
+// openEncryptHeader checks a file header with the key and returns the file ID.
+func openEncryptHeader(aead cipher.AEAD, b []byte) ([]byte, error) {
+	if string(b[:len(encryptMagic)]) != encryptMagic {
+		return nil, fmt.Errorf("not an encrypted log: %w", ErrInvalidFrame)
+	}
+	id := b[len(encryptMagic) : len(encryptMagic)+encryptIDSize]
+	nonce := b[len(encryptMagic)+encryptIDSize : len(encryptMagic)+encryptIDSize+encryptNonceSize]
+	tag := b[len(encryptMagic)+encryptIDSize+encryptNonceSize : encryptHeadSize]
+	if _, err := aead.Open(nil, nonce, tag, b[:len(encryptMagic)+encryptIDSize]); err != nil {
+		return nil, fmt.Errorf("wrong key or corrupted header: %w", ErrInvalidFrame)
+	}
+	return id, nil
+}
+
+// frameWriter encrypts each Write into frames.
+type frameWriter struct {
+	w    io.Writer
+	aead cipher.AEAD
+	id   []byte // file ID
+	seq  uint64 // sequence number of the next frame
+	ad   []byte
+	buf  []byte
+}
+
+func (fw *frameWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > maxFramePlain {
+			chunk = chunk[:maxFramePlain]
+		}
+		if err := fw.writeFrame(chunk); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
+
+func (fw *frameWriter) writeFrame(plain []byte) error {
+	size := encryptNonceSize + len(plain) + encryptTagSize
+	if cap(fw.buf) < frameHeaderSize+size {
+		fw.buf = make([]byte, 0, frameHeaderSize+size)
+	}
+
+	b := fw.buf[:frameHeaderSize+encryptNonceSize]
+	binary.BigEndian.PutUint32(b, uint32(size))
+	nonce := b[frameHeaderSize:]
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	fw.ad = frameAD(fw.ad, uint32(size), fw.id, fw.seq)
+	b = fw.aead.Seal(b, nonce, plain, fw.ad)
+
+	// a frame in a write, so that a failure leaves at most a partial frame at the end
+	if _, err := fw.w.Write(b); err != nil {
+		return err
+	}
+	fw.seq++
+	return nil
+}
+
+// prepareEncrypted prepares a file opened to append encrypted frames,
+// and returns a writer of frames following the last one in the file.
+// It writes a header to an empty file, or checks the header with the key,
+// authenticates frames and cuts off those left broken at the end.
+func prepareEncrypted(f *os.File, aead cipher.AEAD) (*frameWriter, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+
+	head := make([]byte, encryptHeadSize)
+	if size < int64(len(head)) {
+		head = head[:size]
+	}
+	if _, err := f.ReadAt(head, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n := len(head); n < len(encryptMagic) && string(head) != encryptMagic[:n] ||
+		n >= len(encryptMagic) && string(head[:len(encryptMagic)]) != encryptMagic {
+		return nil, fmt.Errorf("%s: not an encrypted log: %w", f.Name(), ErrInvalidFrame)
+	}
+
+	if size < int64(encryptHeadSize) {
+		// empty, or the header partially written
+		head, err := newEncryptHeader(aead)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.Truncate(0); err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(head); err != nil {
+			return nil, err
+		}
+		return &frameWriter{w: f, aead: aead, id: head[len(encryptMagic) : len(encryptMagic)+encryptIDSize]}, nil
+	}
+
+	id, err := openEncryptHeader(aead, head)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name(), err)
+	}
+	fw := &frameWriter{w: f, aead: aead, id: id}
+
+	end, err := lastFrameEnd(f, size, fw)
+	if err != nil {
+		return nil, err
+	}
+	if end == size {
+		return fw, nil
+	}
+
+	// more than a frame can't be left by a crash, except zeros
+	if size-end > frameHeaderSize+maxFrameSize {
+		zeros, err := zeroTail(f, end, size)
+		if err != nil {
+			return nil, err
+		}
+		if !zeros {
+			return nil, fmt.Errorf("%s: at %d: %w", f.Name(), end, ErrInvalidFrame)
+		}
+	}
+	return fw, f.Truncate(end)
+}
+
+// lastFrameEnd authenticates frames and returns the offset after
+// the last one, counting frames by fw.seq.
+func lastFrameEnd(f *os.File, size int64, fw *frameWriter) (int64, error) {
+	off := int64(encryptHeadSize)
+	var hdr [frameHeaderSize]byte
+	var b []byte
+	for off+frameHeaderSize <= size {
+		if _, err := f.ReadAt(hdr[:], off); err != nil {
+			return 0, err
+		}
+		n := int64(binary.BigEndian.Uint32(hdr[:]))
+		if n < encryptNonceSize+encryptTagSize || n > maxFrameSize || off+frameHeaderSize+n > size {
+			break
+		}
+
+		if int64(cap(b)) < n {
+			b = make([]byte, n)
+		}
+		b = b[:n]
+		if _, err := f.ReadAt(b, off+frameHeaderSize); err != nil {
+			return 0, err
+		}
+		fw.ad = frameAD(fw.ad, uint32(n), fw.id, fw.seq)
+		if _, err := fw.aead.Open(b[encryptNonceSize:encryptNonceSize], b[:encryptNonceSize], b[encryptNonceSize:], fw.ad); err != nil {
+			break
+		}
+		fw.seq++
+		off += frameHeaderSize + n
+	}
+	return off, nil
+}
+
+// zeroTail reports whether the file is all zero from off to size.
+func zeroTail(f *os.File, off, size int64) (bool, error) {
+	b := make([]byte, 32*1024)
+	for off < size {
+		n, err := f.ReadAt(b, off)
+		for _, c := range b[:n] {
+			if c != 0 {
+				return false, nil
+			}
+		}
+		off += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+///////////////////////////////////////////////////////////////////////
+
+// frameReader decrypts frames.
+type frameReader struct {
+	r     io.Reader
+	aead  cipher.AEAD
+	id    []byte
+	seq   uint64
+	ad    []byte
+	buf   []byte
+	plain []byte // rest of the last frame
+	err   error
+}
+
+// NewDecryptReader returns a reader of the lines of an encrypted log file
+// written by a file adapter with the key.
+// After the lines of all complete frames, it returns ErrTruncatedFrame
+// if r ends in a frame, and ErrInvalidFrame for a frame which doesn't
+// authenticate, e.g. corrupted, out of order or written with another key.
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	head := make([]byte, encryptHeadSize)
+	if _, err := io.ReadFull(r, head); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("not an encrypted log: %w", ErrInvalidFrame)
+		}
+		return nil, err
+	}
+	id, err := openEncryptHeader(aead, head)
+	if err != nil {
+		return nil, err
+	}
+	return &frameReader{r: r, aead: aead, id: id}, nil
+}
+
+func (fr *frameReader) Read(p []byte) (int, error) {
+	for len(fr.plain) == 0 {
+		if fr.err != nil {
+			return 0, fr.err
+		}
+		fr.plain, fr.err = fr.readFrame()
+	}
+	n := copy(p, fr.plain)
+	fr.plain = fr.plain[n:]
+	return n, nil
+}
+
+func (fr *frameReader) readFrame() ([]byte, error) {
+	var hdr [frameHeaderSize]byte
+	if _, err := io.ReadFull(fr.r, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, ErrTruncatedFrame
+		}
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(hdr[:])
+	if size < encryptNonceSize+encryptTagSize || size > maxFrameSize {
+		return nil, fmt.Errorf("frame %d: %w", fr.seq, ErrInvalidFrame)
+	}
+	if cap(fr.buf) < int(size) {
+		fr.buf = make([]byte, size)
+	}
+	b := fr.buf[:size]
+	if _, err := io.ReadFull(fr.r, b); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrTruncatedFrame
+		}
+		return nil, err
+	}
+
+	fr.ad = frameAD(fr.ad, size, fr.id, fr.seq)
+	plain, err := fr.aead.Open(b[encryptNonceSize:encryptNonceSize], b[:encryptNonceSize], b[encryptNonceSize:], fr.ad)
+	if err != nil {
+		return nil, fmt.Errorf("frame %d: %w", fr.seq, ErrInvalidFrame)
+	}
+	fr.seq++
+	return plain, nil
+}
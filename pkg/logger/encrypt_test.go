@@ -0,0 +1,190 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptedFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	key := bytes.Repeat([]byte{7}, 32)
+
+	logEncrypted := func(msgs ...string) {
+		t.Helper()
+		l := New("file", false)
+		c := NewFileAdapterConfig()
+		c.Filename = filename
+		c.Format = "$msg"
+		c.AutoFlush = true
+		c.EncryptionKey = key
+		if err := l.Attach(c); err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range msgs {
+			l.Information(m)
+		}
+		l.Detach(AdapterFile)
+	}
+
+	readMsgs := func(key []byte) (string, error) {
+		t.Helper()
+		c := NewReaderConfig()
+		c.Format = "$msg"
+		c.Key = key
+		rd, err := OpenReader(filename, c)
+		if err != nil {
+			return "", err
+		}
+		defer rd.Close()
+
+		var msgs []string
+		for {
+			rec, err := rd.Read()
+			if err == io.EOF {
+				return strings.Join(msgs, ","), nil
+			}
+			if err != nil {
+				return "", err
+			}
+			msgs = append(msgs, rec.Msg.(string))
+		}
+	}
+
+	logEncrypted("alpha", "beta")
+	if b := readFile(t, filename); !strings.HasPrefix(b, encryptMagic) || strings.Contains(b, "alpha") {
+		t.Fatalf("not encrypted: %q", b)
+	}
+	if got, err := readMsgs(key); err != nil || got != "alpha,beta" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if _, err := readMsgs(bytes.Repeat([]byte{8}, 32)); !errors.Is(err, ErrInvalidFrame) {
+		t.Errorf("wrong key: got %v", err)
+	}
+	if _, err := readMsgs(nil); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("no key: got %v", err)
+	}
+
+	// crash in the middle of the last frame
+	b := readFile(t, filename)
+	if err := os.WriteFile(filename, []byte(b[:len(b)-5]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dr, err := NewDecryptReader(f, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(dr)
+	f.Close()
+	if string(plain) != "alpha"+lineFeed || err != ErrTruncatedFrame {
+		t.Errorf("truncated: got %q, %v", plain, err)
+	}
+	if got, err := readMsgs(key); err != nil || got != "alpha" {
+		t.Errorf("truncated: got %q, %v", got, err)
+	}
+
+	// the partial frame is cut off before appending
+	logEncrypted("gamma")
+	if got, err := readMsgs(key); err != nil || got != "alpha,gamma" {
+		t.Errorf("recovered: got %q, %v", got, err)
+	}
+
+	// zeros left by a crash, longer than a frame
+	f, err = os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(make([]byte, 2*maxFrameSize))
+	f.Close()
+	logEncrypted("delta")
+	if got, err := readMsgs(key); err != nil || got != "alpha,gamma,delta" {
+		t.Errorf("zero tail: got %q, %v", got, err)
+	}
+
+	// another key fails without touching the file
+	b = readFile(t, filename)
+	l := New("file", false)
+	c := NewFileAdapterConfig()
+	c.Filename = filename
+	c.EncryptionKey = bytes.Repeat([]byte{8}, 32)
+	if err := l.Attach(c); !errors.Is(err, ErrInvalidFrame) {
+		l.Detach(AdapterFile)
+		t.Errorf("another key: got %v", err)
+	}
+	if readFile(t, filename) != b {
+		t.Errorf("another key: file modified")
+	}
+}
+
+func TestEncryptedFrameOrder(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	key := bytes.Repeat([]byte{7}, 16)
+
+	for _, name := range []string{filename, filename + ".other"} {
+		l := New("file", false)
+		c := NewFileAdapterConfig()
+		c.Filename = name
+		c.Format = "$msg"
+		c.AutoFlush = true
+		c.EncryptionKey = key
+		if err := l.Attach(c); err != nil {
+			t.Fatal(err)
+		}
+		l.Information("a")
+		l.Information("b")
+		l.Detach(AdapterFile)
+	}
+
+	// header and frames of the same size
+	split := func(b string) (string, []string) {
+		head, rest := b[:encryptHeadSize], b[encryptHeadSize:]
+		n := len(rest) / 2
+		return head, []string{rest[:n], rest[n:]}
+	}
+	head, frames := split(readFile(t, filename))
+	_, others := split(readFile(t, filename+".other"))
+
+	for name, b := range map[string]string{
+		"reordered":  head + frames[1] + frames[0],
+		"duplicated": head + frames[0] + frames[0],
+		"dropped":    head + frames[1],
+		"moved":      head + frames[0] + others[1],
+	} {
+		dr, err := NewDecryptReader(strings.NewReader(b), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.ReadAll(dr); !errors.Is(err, ErrInvalidFrame) {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+}
+
+func TestEncryptedFileConfig(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(filename, []byte("plain"+lineFeed), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := New("file", false)
+	c := NewFileAdapterConfig()
+	c.Filename = filename
+	c.EncryptionKey = []byte("short")
+	if err := l.Attach(c); err != ErrInvalidConfig {
+		t.Errorf("invalid key: got %v", err)
+	}
+
+	c.EncryptionKey = bytes.Repeat([]byte{7}, 16)
+	if err := l.Attach(c); !errors.Is(err, ErrInvalidFrame) {
+		l.Detach(AdapterFile)
+		t.Errorf("plain file: got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package logger
+
+import "sync/atomic"
+
+// ErrorHandler is called when an adapter fails to write or flush.
+// For async. logger, it is called on the background goroutine,
+// so it must not log to the same logger.
+type ErrorHandler func(id AdapterID, err error)
+
+// SetErrorHandler for singleton
+func SetErrorHandler(h ErrorHandler) { lgr.SetErrorHandler(h) }
+
+// SetErrorHandler sets the handler of adapter errors.
+// nil handler ignores errors.
+func (logger *Logger) SetErrorHandler(h ErrorHandler) {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	// read without lock by asyncProc
+	logger.onError.Store(h)
+}
+
+func (logger *Logger) handleError(id AdapterID, err error) {
+	atomic.AddUint64(&logger.counters.failed, 1)
+	if h, _ := logger.onError.Load().(ErrorHandler); h != nil {
+		h(id, err)
+	}
+}
+
+// fallbackAdapter wraps an adapter to be stored in atomic.Value
+type fallbackAdapter struct {
+	a adapter
+}
+
+// SetFallback for singleton
+func SetFallback(config AdapterConfig) error { return lgr.SetFallback(config) }
+
+// SetFallback sets an adapter which receives records
+// that attached adapters failed to write, e.g. a console adapter writing to stderr.
+// A record is sent to the fallback only when an adapter failed to write it
+// and no other adapter wrote it, so that it's written once. Adapters which
+// skip the record by their Level or Filter don't count as written.
+// The fallback adapter isn't one of attached adapters.
+// nil config removes the fallback adapter.
+func (logger *Logger) SetFallback(config AdapterConfig) error {
+	var fb *fallbackAdapter
+	if config != nil {
+		ad, err := newAdapter(config)
+		if err != nil {
+			return err
+		}
+		fb = &fallbackAdapter{a: ad}
+	}
+
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	logger.flush()
+	if old := logger.loadFallback(); old != nil {
+		old.a.uninit()
+	}
+	logger.fallback.Store(fb)
+	logger.updateAdapters()
+	return nil
+}
+
+func (logger *Logger) loadFallback() *fallbackAdapter {
+	fb, _ := logger.fallback.Load().(*fallbackAdapter)
+	return fb
+}
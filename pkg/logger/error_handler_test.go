@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestFallback(t *testing.T) {
+	var fallback bytes.Buffer
+	var failures []AdapterID
+
+	l := New("app", false)
+	c := NewConsoleAdapterConfig()
+	c.Format = "$msg"
+	c.Color = false
+	c.Writer = failWriter{}
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+
+	fc := NewConsoleAdapterConfig()
+	fc.Format = "fallback $msg"
+	fc.Color = false
+	fc.Writer = &fallback
+	if err := l.SetFallback(fc); err != nil {
+		t.Fatal(err)
+	}
+	l.SetErrorHandler(func(id AdapterID, err error) {
+		failures = append(failures, id)
+	})
+
+	l.Information("lost")
+	if got := fallback.String(); got != "fallback lost"+lineFeed {
+		t.Errorf("fallback: got %q", got)
+	}
+
+	// written by another adapter, not sent to the fallback
+	filename := filepath.Join(t.TempDir(), "app.log")
+	c2 := NewFileAdapterConfig()
+	c2.Filename = filename
+	c2.Format = "$msg"
+	c2.AutoFlush = true
+	if err := l.Attach(c2); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Detach(AdapterFile)
+
+	l.Information("written")
+	if got := fallback.String(); got != "fallback lost"+lineFeed {
+		t.Errorf("fallback: got %q", got)
+	}
+	if got := readFile(t, filename); got != "written"+lineFeed {
+		t.Errorf("file: got %q", got)
+	}
+
+	// another adapter of higher level doesn't write it
+	l.Detach(AdapterFile)
+	c2.Level = LevelError
+	if err := l.Attach(c2); err != nil {
+		t.Fatal(err)
+	}
+	l.Information("skipped")
+	if got := fallback.String(); got != "fallback lost"+lineFeed+"fallback skipped"+lineFeed {
+		t.Errorf("fallback: got %q", got)
+	}
+
+	// another adapter filtering it out doesn't write it
+	l.Detach(AdapterFile)
+	c2.Level = LevelDebug
+	c2.Filter = LevelRange(LevelDebug, LevelWarning)
+	if err := l.Attach(c2); err != nil {
+		t.Fatal(err)
+	}
+	l.Error("filtered")
+	if got := fallback.String(); got != "fallback lost"+lineFeed+"fallback skipped"+lineFeed+"fallback filtered"+lineFeed {
+		t.Errorf("fallback: got %q", got)
+	}
+
+	if len(failures) != 4 || failures[0] != AdapterConsole {
+		t.Errorf("unexpected failures %v", failures)
+	}
+}
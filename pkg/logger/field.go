@@ -0,0 +1,196 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+)
+
+// Field is a key-value pair attached to a log record.
+// Fields are rendered by $fields token of text format
+// and as object members of JSON encoding.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F makes a field.
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Err makes an "error" field.
+// Its wrapped errors (%w and errors.Join) are rendered as "causes" in JSON encoding.
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+///////////////////////////////////////////////////////////////////////
+// error rendering
+///////////////////////////////////////////////////////////////////////
+
+// maximum depth of error causes, guards against cyclic chains
+const maxErrorDepth = 16
+
+type errorInfo struct {
+	Msg    string      `json:"msg"`
+	Causes []errorInfo `json:"causes,omitempty"`
+}
+
+// describeError unwraps both single (Unwrap() error)
+// and multiple (Unwrap() []error) wrapped errors.
+func describeError(err error, depth int) errorInfo {
+	info := errorInfo{Msg: err.Error()}
+	if depth >= maxErrorDepth {
+		return info
+	}
+
+	for _, c := range describeCauses(err) {
+		info.Causes = append(info.Causes, describeError(c, depth+1))
+	}
+	return info
+}
+
+// describeCauses returns non-nil wrapped errors.
+func describeCauses(err error) []error {
+	var causes []error
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, c := range e.Unwrap() {
+			if c != nil {
+				causes = append(causes, c)
+			}
+		}
+	default:
+		if cause := errors.Unwrap(err); cause != nil {
+			causes = []error{cause}
+		}
+	}
+	return causes
+}
+
+// hasError reports whether a record carries an error.
+func (m *message) hasError() bool {
+	if _, ok := m.Msg.(error); ok {
+		return true
+	}
+	for _, f := range m.Fields {
+		if _, ok := f.Value.(error); ok {
+			return true
+		}
+	}
+	return false
+}
+
+///////////////////////////////////////////////////////////////////////
+// stack rendering
+///////////////////////////////////////////////////////////////////////
+
+// maximum number of frames captured for a record
+const maxStackDepth = 32
+
+func callerStack(skip int) []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip+1, pcs)
+	return pcs[:n]
+}
+
+// maximum length of stack dump buffer, longer dumps are truncated
+var maxStackDump = 64 * 1024 * 1024
+
+// estimated length of the dump of a goroutine
+const goroutineDumpLen = 2 * 1024
+
+// dumpStack returns runtime.Stack growing buffer until the dump fits
+// or the buffer reaches maxStackDump.
+func dumpStack(bufLen int, all bool) []byte {
+	// minimum 1024 bytes
+	if bufLen < 1024 {
+		bufLen = 1024
+	}
+
+	for {
+		if bufLen > maxStackDump {
+			bufLen = maxStackDump
+		}
+		buf := make([]byte, bufLen)
+		n := runtime.Stack(buf, all)
+		if n < bufLen || bufLen >= maxStackDump {
+			return buf[:n]
+		}
+		bufLen *= 2
+	}
+}
+
+// appendStack appends frames like runtime.Stack without arguments,
+// each frame is terminated by lineFeed.
+func appendStack(b []byte, pcs []uintptr) []byte {
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		b = append(b, f.Function...)
+		b = append(b, lineFeed...)
+		b = append(b, '\t')
+		b = append(b, f.File...)
+		b = append(b, ':')
+		b = strconv.AppendInt(b, int64(f.Line), 10)
+		b = append(b, lineFeed...)
+		if !more {
+			return b
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////
+// field rendering
+///////////////////////////////////////////////////////////////////////
+
+// appendValue appends a value as fmt "%+v" does.
+// Common types are appended without fmt.
+func appendValue(b []byte, v interface{}) []byte {
+	switch vv := v.(type) {
+	case string:
+		return append(b, vv...)
+	case int:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case int64:
+		return strconv.AppendInt(b, vv, 10)
+	case int32:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case uint:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case uint64:
+		return strconv.AppendUint(b, vv, 10)
+	case uint32:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case float64:
+		return strconv.AppendFloat(b, vv, 'g', -1, 64)
+	case float32:
+		return strconv.AppendFloat(b, float64(vv), 'g', -1, 32)
+	case bool:
+		return strconv.AppendBool(b, vv)
+	case fmt.Formatter:
+		// may render more than Error() or String() with '+' flag
+	case error:
+		return append(b, vv.Error()...)
+	case fmt.Stringer:
+		return append(b, vv.String()...)
+	}
+
+	buf := buffer{b: b}
+	fmt.Fprintf(&buf, "%+v", v)
+	return buf.b
+}
+
+// appendFieldText appends a field value for text format.
+// It is quoted when it contains spaces or quotes.
+func appendFieldText(b []byte, v interface{}) []byte {
+	start := len(b)
+	b = appendValue(b, v)
+	if s := b[start:]; len(s) == 0 || bytes.ContainsAny(s, " \t\r\n\"=") {
+		b = strconv.AppendQuote(b[:start], string(s))
+	}
+	return b
+}
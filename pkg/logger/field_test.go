@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// parkGoroutines starts n goroutines which wait until the returned function is called.
+func parkGoroutines(n int) func() {
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-done
+		}()
+	}
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+func TestDumpStack(t *testing.T) {
+	if got := string(dumpStack(0, false)); !strings.Contains(got, "TestDumpStack") {
+		t.Errorf("current goroutine: got %q", got)
+	}
+
+	release := parkGoroutines(32)
+	defer release()
+
+	// grows from the minimum size until all goroutines fit
+	all := dumpStack(1, true)
+	if n := bytes.Count(all, []byte("parkGoroutines")); n < 32 || len(all) <= 1024 {
+		t.Errorf("all goroutines: got %d goroutines in %d bytes", n, len(all))
+	}
+
+	// truncated at maxStackDump
+	defer func(n int) { maxStackDump = n }(maxStackDump)
+	maxStackDump = 3000
+	if got := dumpStack(1, true); len(got) != maxStackDump {
+		t.Errorf("truncated: got %d bytes", len(got))
+	}
+}
+
+func TestStackAll(t *testing.T) {
+	release := parkGoroutines(64)
+	defer release()
+
+	var out bytes.Buffer
+	l := New("app", false)
+	c := NewConsoleAdapterConfig()
+	c.Format = "$msg"
+	c.Color = false
+	c.Writer = &out
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+
+	l.StackAll(LevelInformation)
+	if n := strings.Count(out.String(), "parkGoroutines"); n < 64 {
+		t.Errorf("got %d parked goroutines", n)
+	}
+}
+
+func TestSetStackLevel(t *testing.T) {
+	var out bytes.Buffer
+	l := New("app", false)
+	c := NewConsoleAdapterConfig()
+	c.Format = "$msg"
+	c.Color = false
+	c.Writer = &out
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.SetStackLevel(Level(1000)); err != ErrInvalidLevel {
+		t.Errorf("got %v, want ErrInvalidLevel", err)
+	}
+	if err := l.SetStackLevel(LevelWarning); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Information("no stack")
+	if got := out.String(); got != "no stack"+lineFeed {
+		t.Errorf("below level: got %q", got)
+	}
+
+	out.Reset()
+	l.Warning("stack")
+	lines := strings.Split(out.String(), lineFeed)
+	if len(lines) < 3 || lines[0] != "stack" || !strings.HasSuffix(lines[1], "TestSetStackLevel") ||
+		!strings.Contains(lines[2], "field_test.go:") {
+		t.Errorf("at level: got %q", out.String())
+	}
+
+	out.Reset()
+	l.ClearStackLevel()
+	l.Error("cleared")
+	if got := out.String(); got != "cleared"+lineFeed {
+		t.Errorf("cleared: got %q", got)
+	}
+}
+
+func TestJSONStack(t *testing.T) {
+	var out bytes.Buffer
+	l := New("app", false)
+	c := NewConsoleAdapterConfig()
+	c.Encoding = EncodingJSON
+	c.Color = false
+	c.Writer = &out
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	l.SetStackLevel(LevelError)
+
+	l.Error("failed")
+	var rec struct {
+		Stack []struct {
+			Function string `json:"function"`
+			File     string `json:"file"`
+			Line     int    `json:"line"`
+		} `json:"stack"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &rec); err != nil {
+		t.Fatalf("%v: %q", err, out.String())
+	}
+	if len(rec.Stack) == 0 {
+		t.Fatalf("no stack: %q", out.String())
+	}
+	f := rec.Stack[0]
+	if !strings.HasSuffix(f.Function, "TestJSONStack") || !strings.HasSuffix(f.File, "field_test.go") || f.Line == 0 {
+		t.Errorf("unexpected frame %+v", f)
+	}
+}
@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Filter decides whether an adapter writes a record.
+// It is checked after the adapter's minimum level.
+type Filter interface {
+	Match(r *Record) bool
+}
+
+// FilterFunc adapts a function to Filter.
+type FilterFunc func(r *Record) bool
+
+// Match calls f(r).
+func (f FilterFunc) Match(r *Record) bool {
+	return f(r)
+}
+
+// LevelRange matches records from min to max level inclusive.
+func LevelRange(min, max Level) Filter {
+	return FilterFunc(func(r *Record) bool {
+		return r.Level.atLeast(min) && max.atLeast(r.Level)
+	})
+}
+
+// MinLevel matches records at level min and above.
+func MinLevel(min Level) Filter {
+	return FilterFunc(func(r *Record) bool {
+		return r.Level.atLeast(min)
+	})
+}
+
+// NameIn matches records of loggers whose names match one of patterns.
+// Patterns are path.Match patterns, e.g. "Default.*".
+func NameIn(patterns ...string) Filter {
+	return FilterFunc(func(r *Record) bool {
+		return matchAny(patterns, r.Name)
+	})
+}
+
+// CallerFile matches records logged in files whose names match one of patterns.
+// Patterns are path.Match patterns, e.g. "audit_*.go".
+func CallerFile(patterns ...string) Filter {
+	return FilterFunc(func(r *Record) bool {
+		return matchAny(patterns, r.File)
+	})
+}
+
+// CallerPackage matches records logged in one of packages.
+// A package is an import path, e.g. "github.com/org/app/audit".
+// A trailing "/..." matches its sub packages too.
+func CallerPackage(pkgs ...string) Filter {
+	return FilterFunc(func(r *Record) bool {
+		pkg := packageOf(r.Function)
+		for _, p := range pkgs {
+			if base := strings.TrimSuffix(p, "/..."); base != p {
+				if pkg == base || strings.HasPrefix(pkg, base+"/") {
+					return true
+				}
+			} else if pkg == p {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// MessageMatch matches records whose messages match re.
+// Non-string messages are rendered with %+v.
+func MessageMatch(re *regexp.Regexp) Filter {
+	return FilterFunc(func(r *Record) bool {
+		if s, ok := r.Msg.(string); ok {
+			return re.MatchString(s)
+		}
+		return re.MatchString(fmt.Sprintf("%+v", r.Msg))
+	})
+}
+
+// And matches records which all filters match.
+func And(filters ...Filter) Filter {
+	return FilterFunc(func(r *Record) bool {
+		for _, f := range filters {
+			if !f.Match(r) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Or matches records which any of filters matches.
+func Or(filters ...Filter) Filter {
+	return FilterFunc(func(r *Record) bool {
+		for _, f := range filters {
+			if f.Match(r) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Not matches records which f doesn't match.
+func Not(f Filter) Filter {
+	return FilterFunc(func(r *Record) bool {
+		return !f.Match(r)
+	})
+}
+
+///////////////////////////////////////////////////////////////////////
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// packageOf returns package path of a function name
+// such as "github.com/org/app/pkg.(*Type).Method".
+func packageOf(function string) string {
+	slash := strings.LastIndexByte(function, '/')
+	if dot := strings.IndexByte(function[slash+1:], '.'); dot != -1 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+	rec := &Record{
+		Name:     "Default.db",
+		Level:    LevelInformation,
+		Function: "github.com/org/app/store/sql.(*DB).Query",
+		File:     "query.go",
+		Msg:      "audit: user deleted",
+	}
+
+	tests := []struct {
+		name string
+		f    Filter
+		want bool
+	}{
+		{"level in range", LevelRange(LevelDebug, LevelInformation), true},
+		{"level out of range", LevelRange(LevelNotice, LevelFatal), false},
+		{"name", NameIn("Default.*"), true},
+		{"name excluded", Not(NameIn("Default.db")), false},
+		{"file", CallerFile("*.go"), true},
+		{"package", CallerPackage("github.com/org/app/store/sql"), true},
+		{"sub package", CallerPackage("github.com/org/app/..."), true},
+		{"other package", CallerPackage("github.com/org/app"), false},
+		{"message", MessageMatch(regexp.MustCompile(`^audit:`)), true},
+		{"and", And(NameIn("Default.db"), MessageMatch(regexp.MustCompile(`^debug`))), false},
+		{"or", Or(NameIn("http"), MessageMatch(regexp.MustCompile(`^audit`))), true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.f.Match(rec); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConsoleAdapterFilter(t *testing.T) {
+	var out bytes.Buffer
+	l := New("app", false)
+	c := NewConsoleAdapterConfig()
+	c.Format = "$name $msg"
+	c.Color = false
+	c.Writer = &out
+	c.Filter = Or(NameIn("app.db"), MessageMatch(regexp.MustCompile(`^audit:`)))
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Information("dropped")
+	l.Information("audit: kept")
+	l.Named("db").Information("kept")
+	l.Named("http").Information("dropped")
+
+	want := "app audit: kept" + lineFeed + "app.db kept" + lineFeed
+	if got := out.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFileAdapterFilter(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+
+	l := New("app", false)
+	c := NewFileAdapterConfig()
+	c.Filename = filename
+	c.Format = "$msg"
+	c.AutoFlush = true
+	c.Filter = And(CallerFile("filter_test.go"), Not(LevelRange(LevelDebug, LevelInformation)))
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Detach(AdapterFile)
+
+	l.Information("dropped")
+	l.Warning("kept")
+	l.Error("kept too")
+
+	if got, want := readFile(t, filename), "kept"+lineFeed+"kept too"+lineFeed; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
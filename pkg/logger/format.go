@@ -0,0 +1,603 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+///////////////////////////////////////////////////////////////////////
+// log format
+///////////////////////////////////////////////////////////////////////
+
+// format tokens
+//
+//	$token          simple token, name is the longest run of letters
+//	${token}        same as $token, useful when followed by letters
+//	${token:WIDTH}  padded token, negative WIDTH aligns left
+//	${time:LAYOUT}  time with layout name or Go time layout
+//	${unix:UNIT}    unix time with UNIT s(default), ms, us or ns
+//	$$              literal '$'
+var formatTokens = []string{
+	"name", "ltime", "stime", "ts", "time", "unix",
+	"ilevel", "slevel", "function", "file", "pkgfile", "path", "line",
+	"msg", "fields", "pid", "hostname", "goroutine",
+}
+
+type formatToken struct {
+	name  string // empty for literal text
+	param string
+	text  string
+	pos   int
+}
+
+// time layout names accepted by ${time:NAME}
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"Stamp":       time.Stamp,
+	"StampMilli":  time.StampMilli,
+	"StampMicro":  time.StampMicro,
+	"StampNano":   time.StampNano,
+}
+
+// loadLocation returns time zone for adapter config.
+// Empty name means local time zone.
+func loadLocation(name string) (*time.Location, error) {
+	if len(name) == 0 {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+func isTokenLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isFormatToken(name string) bool {
+	for _, t := range formatTokens {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+func formatError(format string, pos int, reason string) error {
+	return fmt.Errorf("%w: %s at %d in %q", ErrInvalidFormat, reason, pos, format)
+}
+
+// ValidateFormat checks a format string as Attach does,
+// e.g. to check a configuration before attaching an adapter.
+func ValidateFormat(format string) error {
+	tokens, err := parseFormat(format)
+	if err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		if len(t.name) == 0 {
+			continue
+		}
+		if _, _, err := compileToken(t, 0, time.UTC); err != nil {
+			return formatError(format, t.pos, err.Error())
+		}
+	}
+	return nil
+}
+
+// parseFormat splits format string into literal texts and tokens.
+func parseFormat(format string) ([]formatToken, error) {
+	var (
+		tokens []formatToken
+		lit    strings.Builder
+	)
+
+	flushLiteral := func() {
+		if lit.Len() > 0 {
+			tokens = append(tokens, formatToken{text: lit.String()})
+			lit.Reset()
+		}
+	}
+
+	for i := 0; i < len(format); {
+		c := format[i]
+		if c != '$' {
+			lit.WriteByte(c)
+			i++
+			continue
+		}
+
+		next := byte(0)
+		if i+1 < len(format) {
+			next = format[i+1]
+		}
+
+		t := formatToken{pos: i}
+		switch {
+		case next == '$':
+			lit.WriteByte('$')
+			i += 2
+			continue
+		case next == '{':
+			end := strings.IndexByte(format[i:], '}')
+			if end == -1 {
+				return nil, formatError(format, i, "unterminated '${'")
+			}
+			body := format[i+2 : i+end]
+			if k := strings.IndexByte(body, ':'); k != -1 {
+				t.name, t.param = body[:k], body[k+1:]
+			} else {
+				t.name = body
+			}
+			i += end + 1
+		case isTokenLetter(next):
+			j := i + 1
+			for j < len(format) && isTokenLetter(format[j]) {
+				j++
+			}
+			t.name = format[i+1 : j]
+			i = j
+		default:
+			return nil, formatError(format, i, "dangling '$' (use $$ for literal '$')")
+		}
+
+		if !isFormatToken(t.name) {
+			return nil, formatError(format, t.pos, fmt.Sprintf("unknown token %q", t.name))
+		}
+
+		flushLiteral()
+		tokens = append(tokens, t)
+	}
+	flushLiteral()
+
+	return tokens, nil
+}
+
+///////////////////////////////////////////////////////////////////////
+// encoder
+///////////////////////////////////////////////////////////////////////
+
+// capture is a set of record data which is collected at logging site
+// only when an adapter requires it.
+type capture uint8
+
+const (
+	captureGoroutine capture = 1 << iota
+)
+
+// Encoding is output encoding of an adapter.
+type Encoding int
+
+// encodings
+const (
+	EncodingText Encoding = iota // formatted by Format string
+	EncodingJSON                 // a JSON object per line, Format is ignored
+)
+
+// MultiLine is a policy of writing line breaks in a text record,
+// which are from messages or stack traces. Field values with line breaks
+// are quoted, and JSON encoding always writes a record in a line.
+type MultiLine int
+
+// multi-line policies
+const (
+	MultiLineKeep   MultiLine = iota // write line breaks as they are
+	MultiLineIndent                  // prefix continuation lines with LineMarker
+	MultiLineEscape                  // write line breaks as \r and \n and '\' as \\, a record is a line
+)
+
+// DefaultLineMarker is the default prefix of continuation lines.
+// Line-oriented collectors commonly join lines starting with white spaces
+// to the previous line.
+const DefaultLineMarker = "\t"
+
+// writerConfig is output options of an adapter.
+// It is comparable, adapters with the same options share an encoder.
+type writerConfig struct {
+	format     string
+	encoding   Encoding
+	maxMsgLen  uint32
+	timeZone   string
+	multiLine  MultiLine
+	lineMarker string
+}
+
+// encodeOp appends a part of a record to b.
+type encodeOp func(b []byte, msg *message) []byte
+
+// encoder renders a record into a line terminated by lineFeed.
+// Empty output means the record is not written at all.
+type encoder struct {
+	encode encodeOp
+	caps   capture
+}
+
+// maximum number of cached encoders
+const maxEncoders = 64
+
+var (
+	encoderLock  sync.Mutex
+	encoderCache = map[writerConfig]*encoder{}
+)
+
+// makeEncoder compiles output options into an encoder.
+// Encoders are cached so that a record is encoded once
+// for all adapters of the same options. When the cache is full,
+// e.g. by options changed over time, an arbitrary entry is evicted;
+// adapters keep their encoders, new ones just don't share it.
+func makeEncoder(c writerConfig) (*encoder, error) {
+	encoderLock.Lock()
+	defer encoderLock.Unlock()
+
+	if enc, ok := encoderCache[c]; ok {
+		return enc, nil
+	}
+
+	loc, err := loadLocation(c.timeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	var enc *encoder
+	switch c.encoding {
+	case EncodingText:
+		enc, err = makeTextEncoder(c.format, c.maxMsgLen, loc, c.multiLine, c.lineMarker)
+	case EncodingJSON:
+		enc = makeJSONEncoder(c.maxMsgLen, loc)
+	default:
+		err = ErrInvalidConfig
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(encoderCache) >= maxEncoders {
+		for k := range encoderCache {
+			delete(encoderCache, k)
+			break
+		}
+	}
+	encoderCache[c] = enc
+	return enc, nil
+}
+
+func makeTextEncoder(format string, maxMsgLen uint32, loc *time.Location, policy MultiLine, marker string) (*encoder, error) {
+	switch policy {
+	case MultiLineKeep, MultiLineEscape:
+	case MultiLineIndent:
+		if len(marker) == 0 {
+			marker = DefaultLineMarker
+		}
+	default:
+		return nil, ErrInvalidConfig
+	}
+
+	if len(format) == 0 {
+		return &encoder{encode: func(b []byte, msg *message) []byte {
+			return b
+		}}, nil
+	}
+
+	tokens, err := parseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ops  []encodeOp
+		caps capture
+	)
+	for _, t := range tokens {
+		if len(t.name) == 0 {
+			text := t.text
+			ops = append(ops, func(b []byte, msg *message) []byte {
+				return append(b, text...)
+			})
+			continue
+		}
+
+		op, c, err := compileToken(t, maxMsgLen, loc)
+		if err != nil {
+			return nil, formatError(format, t.pos, err.Error())
+		}
+		if policy != MultiLineKeep && t.name != "fields" {
+			// field values with line breaks are quoted
+			op = multiLineOp(op, policy, marker)
+		}
+		ops = append(ops, op)
+		caps |= c
+	}
+
+	return &encoder{
+		encode: func(b []byte, msg *message) []byte {
+			for _, op := range ops {
+				b = op(b, msg)
+			}
+			if len(msg.stack) > 0 {
+				return appendStackLines(b, msg.stack, policy, marker)
+			}
+			return append(b, lineFeed...)
+		},
+		caps: caps,
+	}, nil
+}
+
+// multiLineOp makes op write line breaks by policy.
+func multiLineOp(op encodeOp, policy MultiLine, marker string) encodeOp {
+	special := "\n"
+	if policy == MultiLineEscape {
+		special = "\\\r\n"
+	}
+	return func(b []byte, msg *message) []byte {
+		start := len(b)
+		b = op(b, msg)
+		if bytes.IndexAny(b[start:], special) < 0 {
+			return b
+		}
+
+		tmp := getBuffer()
+		tmp.b = append(tmp.b, b[start:]...)
+		b = appendMultiLine(b[:start], tmp.b, policy, marker)
+		putBuffer(tmp)
+		return b
+	}
+}
+
+// appendMultiLine appends text writing line breaks by policy.
+// MultiLineEscape escapes backslashes too, so that the text can be unescaped.
+func appendMultiLine(b, text []byte, policy MultiLine, marker string) []byte {
+	for _, c := range text {
+		switch {
+		case policy == MultiLineEscape && c == '\\':
+			b = append(b, '\\', '\\')
+		case policy == MultiLineEscape && c == '\r':
+			b = append(b, '\\', 'r')
+		case policy == MultiLineEscape && c == '\n':
+			b = append(b, '\\', 'n')
+		case policy == MultiLineIndent && c == '\n':
+			b = append(b, '\n')
+			b = append(b, marker...)
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
+
+// appendStackLines appends lineFeed and stack frames following a record,
+// as continuation lines by policy.
+func appendStackLines(b []byte, pcs []uintptr, policy MultiLine, marker string) []byte {
+	if policy == MultiLineKeep {
+		b = append(b, lineFeed...)
+		return appendStack(b, pcs)
+	}
+
+	// the stack without its last lineFeed, following lineFeed of the record
+	tmp := getBuffer()
+	tmp.b = append(tmp.b, lineFeed...)
+	tmp.b = appendStack(tmp.b, pcs)
+	b = appendMultiLine(b, tmp.b[:len(tmp.b)-len(lineFeed)], policy, marker)
+	putBuffer(tmp)
+	return append(b, lineFeed...)
+}
+
+// funcPackage returns the package path of a function name of runtime,
+// e.g. "example.com/app/db" of "example.com/app/db.(*Conn).Close".
+// Dots in the last element of the path are escaped as "%2e" by runtime.
+func funcPackage(function string) string {
+	i := strings.LastIndexByte(function, '/') + 1
+	j := strings.IndexByte(function[i:], '.')
+	if j < 0 {
+		return ""
+	}
+	pkg := function[:i+j]
+	if strings.Contains(pkg[i:], "%2e") {
+		pkg = pkg[:i] + strings.ReplaceAll(pkg[i:], "%2e", ".")
+	}
+	return pkg
+}
+
+// compileToken returns an op which appends a token.
+func compileToken(t formatToken, maxMsgLen uint32, loc *time.Location) (op encodeOp, caps capture, err error) {
+	switch t.name {
+	case "time":
+		layout := t.param
+		if l, ok := timeLayouts[t.param]; ok {
+			layout = l
+		} else if len(layout) == 0 {
+			layout = time.RFC3339
+		}
+		return func(b []byte, msg *message) []byte {
+			return msg.Time.In(loc).AppendFormat(b, layout)
+		}, 0, nil
+	case "unix":
+		var div int64
+		switch t.param {
+		case "", "s":
+			div = 1e9
+		case "ms":
+			div = 1e6
+		case "us":
+			div = 1e3
+		case "ns":
+			div = 1
+		default:
+			return nil, 0, fmt.Errorf("unknown unix time unit %q", t.param)
+		}
+		return func(b []byte, msg *message) []byte {
+			return strconv.AppendInt(b, msg.Time.UnixNano()/div, 10)
+		}, 0, nil
+	}
+
+	var width int
+	if len(t.param) > 0 {
+		if width, err = strconv.Atoi(t.param); err != nil {
+			return nil, 0, fmt.Errorf("invalid width %q of token %q", t.param, t.name)
+		}
+	}
+
+	switch t.name {
+	case "name":
+		op = func(b []byte, msg *message) []byte {
+			return append(b, msg.Name...)
+		}
+	case "ltime":
+		op = func(b []byte, msg *message) []byte {
+			return msg.Time.In(loc).AppendFormat(b, "2006-01-02 15:04:05.000")
+		}
+	case "stime":
+		op = func(b []byte, msg *message) []byte {
+			return msg.Time.In(loc).AppendFormat(b, "15:04:05.000")
+		}
+	case "ts":
+		op = func(b []byte, msg *message) []byte {
+			return strconv.AppendInt(b, msg.Time.UnixNano()/1e3, 10)
+		}
+	case "ilevel":
+		op = func(b []byte, msg *message) []byte {
+			return strconv.AppendInt(b, int64(msg.Level), 10)
+		}
+	case "slevel":
+		op = func(b []byte, msg *message) []byte {
+			return append(b, msg.Level.String()...)
+		}
+	case "function":
+		op = func(b []byte, msg *message) []byte {
+			return append(b, msg.Function...)
+		}
+	case "file":
+		op = func(b []byte, msg *message) []byte {
+			return append(b, msg.File...)
+		}
+	case "path":
+		op = func(b []byte, msg *message) []byte {
+			return append(b, msg.path...)
+		}
+	case "pkgfile":
+		op = func(b []byte, msg *message) []byte {
+			if pkg := funcPackage(msg.Function); len(pkg) > 0 {
+				b = append(b, pkg...)
+				b = append(b, '/')
+			}
+			return append(b, msg.File...)
+		}
+	case "line":
+		op = func(b []byte, msg *message) []byte {
+			return strconv.AppendInt(b, int64(msg.Line), 10)
+		}
+	case "msg":
+		op = func(b []byte, msg *message) []byte {
+			return appendMsg(b, msg, maxMsgLen)
+		}
+	case "fields":
+		op = func(b []byte, msg *message) []byte {
+			for _, f := range msg.Fields {
+				b = append(b, ' ')
+				b = append(b, f.Key...)
+				b = append(b, '=')
+				b = appendFieldText(b, f.Value)
+			}
+			return b
+		}
+	case "pid":
+		pid := strconv.Itoa(os.Getpid())
+		op = func(b []byte, msg *message) []byte {
+			return append(b, pid...)
+		}
+	case "hostname":
+		host, err := os.Hostname()
+		if err != nil {
+			host = "unknown"
+		}
+		op = func(b []byte, msg *message) []byte {
+			return append(b, host...)
+		}
+	case "goroutine":
+		caps = captureGoroutine
+		op = func(b []byte, msg *message) []byte {
+			return strconv.AppendUint(b, msg.goroutine, 10)
+		}
+	}
+
+	if width != 0 {
+		op = padOp(op, width)
+	}
+	return op, caps, nil
+}
+
+// padOp pads output of op with spaces to width runes.
+// Negative width aligns left.
+func padOp(op encodeOp, width int) encodeOp {
+	left := width < 0
+	if left {
+		width = -width
+	}
+
+	return func(b []byte, msg *message) []byte {
+		start := len(b)
+		b = op(b, msg)
+		pad := width - utf8.RuneCount(b[start:])
+		if pad <= 0 {
+			return b
+		}
+
+		for i := 0; i < pad; i++ {
+			b = append(b, ' ')
+		}
+		if !left {
+			copy(b[start+pad:], b[start:len(b)-pad])
+			for i := start; i < start+pad; i++ {
+				b[i] = ' '
+			}
+		}
+		return b
+	}
+}
+
+// appendMsg appends message and truncates it to maxMsgLen bytes
+// at a boundary of UTF-8 sequences.
+// maxMsgLen 0 means no truncation.
+func appendMsg(b []byte, msg *message, maxMsgLen uint32) []byte {
+	start := len(b)
+	b = appendValue(b, msg.Msg)
+	if maxMsgLen > 0 && uint32(len(b)-start) > maxMsgLen {
+		end := start + int(maxMsgLen)
+		for end > start && !utf8.RuneStart(b[end]) {
+			end--
+		}
+		b = append(b[:end], " ..."...)
+	}
+	return b
+}
+
+// goroutineID returns current goroutine's ID
+// parsed from the header of runtime.Stack: "goroutine 123 [running]:".
+func goroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	b := buf[len("goroutine "):n]
+
+	var id uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			break
+		}
+		id = id*10 + uint64(c-'0')
+	}
+	return id
+}
@@ -0,0 +1,231 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+///////////////////////////////////////////////////////////////////////
+// JSON encoder
+///////////////////////////////////////////////////////////////////////
+
+// makeJSONEncoder makes an encoder which outputs a JSON object per line:
+//
+//	{"time":"...","level":"ERR","name":"...","msg":"...",
+//	 "function":"...","file":"...","line":1,<fields>,
+//	 "stack":[{"function":"...","file":"...","line":1},...]}
+//
+// An error message is also rendered as "error" field with its causes,
+// unless the record already has an error field.
+// Field keys colliding with the keys above, and keys starting with
+// "fields.", are prefixed with "fields.", e.g. "fields.time".
+func makeJSONEncoder(maxMsgLen uint32, loc *time.Location) *encoder {
+	return &encoder{encode: func(b []byte, msg *message) []byte {
+		b = append(b, `{"time":"`...)
+		b = msg.Time.In(loc).AppendFormat(b, time.RFC3339Nano)
+		b = append(b, `","level":`...)
+		b = appendJSONString(b, msg.Level.String())
+		b = append(b, `,"name":`...)
+		b = appendJSONString(b, msg.Name)
+		b = append(b, `,"msg":`...)
+		b = appendJSONMsg(b, msg, maxMsgLen)
+		b = append(b, `,"function":`...)
+		b = appendJSONString(b, msg.Function)
+		b = append(b, `,"file":`...)
+		b = appendJSONString(b, msg.File)
+		b = append(b, `,"line":`...)
+		b = strconv.AppendInt(b, int64(msg.Line), 10)
+
+		errorField := false
+		for _, f := range msg.Fields {
+			if _, ok := f.Value.(error); ok || f.Key == "error" {
+				errorField = true
+			}
+			b = append(b, ',')
+			b = appendJSONKey(b, f.Key)
+			b = append(b, ':')
+			b = appendJSONValue(b, f.Value)
+		}
+		if err, ok := msg.Msg.(error); ok && !errorField {
+			b = append(b, `,"error":`...)
+			b = appendJSONError(b, err, 0)
+		}
+
+		if len(msg.stack) > 0 {
+			b = append(b, `,"stack":`...)
+			b = appendJSONStack(b, msg.stack)
+		}
+
+		b = append(b, '}')
+		return append(b, lineFeed...)
+	}}
+}
+
+// prefix of field keys colliding with keys of the record
+const jsonFieldPrefix = "fields."
+
+// keys of the record in a JSON object
+var jsonReserved = map[string]bool{
+	"time": true, "level": true, "name": true, "msg": true,
+	"function": true, "file": true, "line": true, "stack": true,
+}
+
+// appendJSONKey appends a field key, prefixed if it collides.
+func appendJSONKey(b []byte, key string) []byte {
+	if jsonReserved[key] || strings.HasPrefix(key, jsonFieldPrefix) {
+		key = jsonFieldPrefix + key
+	}
+	return appendJSONString(b, key)
+}
+
+// appendJSONMsg appends message as a JSON string.
+func appendJSONMsg(b []byte, msg *message, maxMsgLen uint32) []byte {
+	if s, ok := msg.Msg.(string); ok && (maxMsgLen == 0 || uint32(len(s)) <= maxMsgLen) {
+		return appendJSONString(b, s)
+	}
+
+	tmp := getBuffer()
+	tmp.b = appendMsg(tmp.b, msg, maxMsgLen)
+	b = appendJSONString(b, string(tmp.b))
+	putBuffer(tmp)
+	return b
+}
+
+// appendJSONValue appends a field value.
+// Values which can't be marshaled are rendered as strings.
+func appendJSONValue(b []byte, v interface{}) []byte {
+	switch vv := v.(type) {
+	case nil:
+		return append(b, "null"...)
+	case string:
+		return appendJSONString(b, vv)
+	case int:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case int64:
+		return strconv.AppendInt(b, vv, 10)
+	case int32:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case uint:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case uint64:
+		return strconv.AppendUint(b, vv, 10)
+	case uint32:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case float64:
+		if !math.IsNaN(vv) && !math.IsInf(vv, 0) {
+			return strconv.AppendFloat(b, vv, 'g', -1, 64)
+		}
+	case bool:
+		return strconv.AppendBool(b, vv)
+	case error:
+		return appendJSONError(b, vv, 0)
+	case json.Marshaler:
+		// marshaled below
+	case fmt.Stringer:
+		return appendJSONString(b, vv.String())
+	}
+
+	if e, err := json.Marshal(v); err == nil {
+		return append(b, e...)
+	}
+	tmp := getBuffer()
+	tmp.b = appendValue(tmp.b, v)
+	b = appendJSONString(b, string(tmp.b))
+	putBuffer(tmp)
+	return b
+}
+
+// appendJSONError appends an error as {"msg":"...","causes":[...]}.
+// Both single (Unwrap() error) and multiple (Unwrap() []error)
+// wrapped errors are rendered as causes.
+func appendJSONError(b []byte, err error, depth int) []byte {
+	b = append(b, `{"msg":`...)
+	b = appendJSONString(b, err.Error())
+	if depth < maxErrorDepth {
+		if causes := describeCauses(err); len(causes) > 0 {
+			b = append(b, `,"causes":[`...)
+			for i, c := range causes {
+				if i > 0 {
+					b = append(b, ',')
+				}
+				b = appendJSONError(b, c, depth+1)
+			}
+			b = append(b, ']')
+		}
+	}
+	return append(b, '}')
+}
+
+func appendJSONStack(b []byte, pcs []uintptr) []byte {
+	b = append(b, '[')
+	frames := runtime.CallersFrames(pcs)
+	for first := true; ; first = false {
+		f, more := frames.Next()
+		if !first {
+			b = append(b, ',')
+		}
+		b = append(b, `{"function":`...)
+		b = appendJSONString(b, f.Function)
+		b = append(b, `,"file":`...)
+		b = appendJSONString(b, f.File)
+		b = append(b, `,"line":`...)
+		b = strconv.AppendInt(b, int64(f.Line), 10)
+		b = append(b, '}')
+		if !more {
+			break
+		}
+	}
+	return append(b, ']')
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s as a quoted JSON string.
+// Invalid UTF-8 is replaced with U+FFFD like encoding/json does.
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
@@ -0,0 +1,300 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testMessage() *message {
+	return &message{
+		Record: Record{
+			Name:     "test",
+			Time:     time.Date(2021, 3, 4, 5, 6, 7, 8000000, time.UTC),
+			Level:    LevelWarning,
+			Function: "main.run",
+			File:     "main.go",
+			Line:     42,
+			Msg:      "hello",
+		},
+		path: "/src/app/main.go",
+	}
+}
+
+func TestMakeEncoder(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"$ltime [$slevel] $msg ($file:$line)", "2021-03-04 05:06:07.008 [WRN] hello (main.go:42)"},
+		{"${slevel:-5}|${line:4}|$name", "WRN  |  42|test"},
+		{"$msg $msg $$ts 100%", "hello hello $ts 100%"},
+		{"${time:RFC3339} ${unix} ${unix:ms}", "2021-03-04T05:06:07Z 1614834367 1614834367008"},
+		{"${time:2006/01/02} $pkgfile $path", "2021/03/04 main/main.go /src/app/main.go"},
+		{"$$tsx|cost $$|50$$ $${msg", "$tsx|cost $|50$ ${msg"},
+		{"${msg:7}|${ilevel:-3}|$msg$fields", "  hello|3  |hello n=1 s=\"a b\" d=1.5s"},
+	}
+
+	for _, tt := range tests {
+		enc, err := makeEncoder(writerConfig{format: tt.format, timeZone: "UTC"})
+		if err != nil {
+			t.Fatalf("%q: %v", tt.format, err)
+		}
+
+		msg := testMessage()
+		msg.Fields = []Field{F("n", 1), F("s", "a b"), F("d", 1500*time.Millisecond)}
+		if got := string(enc.encode(nil, msg)); got != tt.want+lineFeed {
+			t.Errorf("%q: got %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestEncoderCache(t *testing.T) {
+	c := writerConfig{format: "$msg cached"}
+	first, err := makeEncoder(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc, _ := makeEncoder(c); enc != first {
+		t.Error("encoder of the same options isn't shared")
+	}
+
+	for i := 0; i < 2*maxEncoders; i++ {
+		if _, err := makeEncoder(writerConfig{format: fmt.Sprintf("$msg %d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	encoderLock.Lock()
+	n := len(encoderCache)
+	encoderLock.Unlock()
+	if n != maxEncoders {
+		t.Errorf("got %d cached encoders, want %d", n, maxEncoders)
+	}
+}
+
+func TestTimeZone(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"$ltime|$stime", "2021-03-04 14:06:07.008|14:06:07.008"},
+		{"${time:RFC3339Nano}|${time:Kitchen}", "2021-03-04T14:06:07.008+09:00|2:06PM"},
+		{"${time:2006-01-02 MST}|${unix:us}|$ts", "2021-03-04 JST|1614834367008000|1614834367008000"},
+	}
+
+	for _, tt := range tests {
+		enc, err := makeEncoder(writerConfig{format: tt.format, timeZone: "Asia/Tokyo"})
+		if err != nil {
+			t.Fatalf("%q: %v", tt.format, err)
+		}
+		if got := string(enc.encode(nil, testMessage())); got != tt.want+lineFeed {
+			t.Errorf("%q: got %q, want %q", tt.format, got, tt.want)
+		}
+	}
+
+	if _, err := makeEncoder(writerConfig{format: "$ltime", timeZone: "Nowhere/City"}); err == nil {
+		t.Error("unknown time zone: got no error")
+	}
+}
+
+func TestMakeEncoderInvalid(t *testing.T) {
+	for _, format := range []string{"${line:x}", "${unix:h}", "$levle", "cost $", "${msg"} {
+		if _, err := makeEncoder(writerConfig{format: format}); !errors.Is(err, ErrInvalidFormat) {
+			t.Errorf("%q: got %v, want ErrInvalidFormat", format, err)
+		}
+	}
+
+	l := New("app", false)
+	c := NewConsoleAdapterConfig()
+	c.Format = "[$levle] $msg"
+	if err := l.Attach(c); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Attach: got %v, want ErrInvalidFormat", err)
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	for _, format := range []string{"$unknown", "$tsx", "cost $", "${msg", "${line:x}"} {
+		if err := ValidateFormat(format); !errors.Is(err, ErrInvalidFormat) {
+			t.Errorf("%q: got %v, want ErrInvalidFormat", format, err)
+		}
+	}
+	if err := ValidateFormat(DefaultFormat + " $$ ${time:RFC3339}"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFuncPackage(t *testing.T) {
+	for function, want := range map[string]string{
+		"main.run":                         "main",
+		"example.com/app/db.(*Conn).Close": "example.com/app/db",
+		"example.com/app/db.Open.func1":    "example.com/app/db",
+		"gopkg.in/yaml%2ev2.Unmarshal":     "gopkg.in/yaml.v2",
+		"null":                             "",
+	} {
+		if got := funcPackage(function); got != want {
+			t.Errorf("%q: got %q, want %q", function, got, want)
+		}
+	}
+}
+
+type joinedError []error
+
+func (e joinedError) Error() string   { return "joined" }
+func (e joinedError) Unwrap() []error { return e }
+
+func TestJSONEncoderError(t *testing.T) {
+	enc, err := makeEncoder(writerConfig{encoding: EncodingJSON, timeZone: "UTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := testMessage()
+	msg.Fields = []Field{Err(fmt.Errorf("load: %w", joinedError{errors.New("a"), errors.New("b")})), F("n", 1)}
+
+	line := enc.encode(nil, msg)
+
+	var rec struct {
+		Msg   string `json:"msg"`
+		Level string `json:"level"`
+		N     int    `json:"n"`
+		Error struct {
+			Msg    string `json:"msg"`
+			Causes []struct {
+				Msg    string `json:"msg"`
+				Causes []struct {
+					Msg string `json:"msg"`
+				} `json:"causes"`
+			} `json:"causes"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(line, &rec); err != nil {
+		t.Fatalf("%v: %s", err, line)
+	}
+
+	if rec.Msg != "hello" || rec.Level != "WRN" || rec.N != 1 || rec.Error.Msg != "load: joined" {
+		t.Errorf("unexpected record: %s", line)
+	}
+	if len(rec.Error.Causes) != 1 || len(rec.Error.Causes[0].Causes) != 2 || rec.Error.Causes[0].Causes[1].Msg != "b" {
+		t.Errorf("unexpected causes: %s", line)
+	}
+}
+
+func TestJSONFieldCollision(t *testing.T) {
+	enc, err := makeEncoder(writerConfig{encoding: EncodingJSON, timeZone: "UTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := testMessage()
+	msg.Msg = errors.New("failed")
+	msg.Fields = []Field{F("time", "noon"), F("line", "x"), F("fields.msg", 1), F("error", "text"), F("user", "a")}
+	line := string(enc.encode(nil, msg))
+
+	// keys in order, without duplicates
+	var keys []string
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.Token()
+	for dec.More() {
+		key, _ := dec.Token()
+		keys = append(keys, key.(string))
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "time level name msg function file line fields.time fields.line fields.fields.msg error user"
+	if got := strings.Join(keys, " "); got != want {
+		t.Errorf("got keys %q, want %q", got, want)
+	}
+
+	// read back with the original keys
+	rec := &Record{}
+	if !parseJSONRecord(strings.TrimSuffix(line, lineFeed), rec) {
+		t.Fatalf("can't parse %q", line)
+	}
+	if len(rec.Fields) != 5 || rec.Fields[0].Key != "time" || rec.Fields[2].Key != "fields.msg" || rec.Line != 42 {
+		t.Errorf("unexpected record %+v", rec)
+	}
+}
+
+func TestAppendJSONString(t *testing.T) {
+	for _, s := range []string{"plain", "quote \" back \\", "line\nfeed\ttab\x01", "utf-8 \u00e9\u4e16", "bad \xff byte"} {
+		var got string
+		if err := json.Unmarshal(appendJSONString(nil, s), &got); err != nil {
+			t.Fatalf("%q: %v", s, err)
+		}
+		want, _ := json.Marshal(s)
+		if e, _ := json.Marshal(got); string(e) != string(want) {
+			t.Errorf("%q: got %s, want %s", s, e, want)
+		}
+	}
+}
+
+func TestMultiLine(t *testing.T) {
+	tests := []struct {
+		policy MultiLine
+		marker string
+		want   string
+	}{
+		{MultiLineKeep, "", "[WRN] a\nb\r\nc\\n n=\"x\\ny\""},
+		{MultiLineIndent, "", "[WRN] a\n\tb\r\n\tc\\n n=\"x\\ny\""},
+		{MultiLineIndent, " | ", "[WRN] a\n | b\r\n | c\\n n=\"x\\ny\""},
+		{MultiLineEscape, "", `[WRN] a\nb\r\nc\\n n="x\ny"`},
+	}
+
+	for _, tt := range tests {
+		enc, err := makeEncoder(writerConfig{format: "[$slevel] $msg$fields", multiLine: tt.policy, lineMarker: tt.marker})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg := testMessage()
+		msg.Msg = "a\nb\r\nc\\n"
+		msg.Fields = []Field{F("n", "x\ny")}
+		if got := string(enc.encode(nil, msg)); got != tt.want+lineFeed {
+			t.Errorf("policy %d: got %q, want %q", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestMultiLineStack(t *testing.T) {
+	msg := testMessage()
+	msg.stack = callerStack(1)
+	frames := string(appendStack(nil, msg.stack))
+	frames = strings.TrimSuffix(frames, lineFeed)
+
+	tests := []struct {
+		policy MultiLine
+		want   string
+	}{
+		{MultiLineKeep, "[hello]" + lineFeed + frames},
+		{MultiLineIndent, "[hello]" + lineFeed + ">" + strings.ReplaceAll(frames, "\n", "\n>")},
+		{MultiLineEscape, "[hello]" + strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(lineFeed+frames)},
+	}
+
+	for _, tt := range tests {
+		enc, err := makeEncoder(writerConfig{format: "[$msg]", multiLine: tt.policy, lineMarker: ">"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(enc.encode(nil, msg)); got != tt.want+lineFeed {
+			t.Errorf("policy %d: got %q, want %q", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestMaxLengthRune(t *testing.T) {
+	enc, err := makeEncoder(writerConfig{format: "$msg", maxMsgLen: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := testMessage()
+	msg.Msg = "a세계"
+	if got, want := string(enc.encode(nil, msg)), "a세 ..."+lineFeed; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
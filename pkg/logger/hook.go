@@ -0,0 +1,78 @@
+package logger
+
+// Hook is called for each record before it is written to adapters.
+// A hook may modify the record, e.g. add fields or rewrite message,
+// and returns false to drop the record.
+//
+// Hooks run in registration order on the logging goroutine for sync. logger
+// and on the background goroutine for async. logger,
+// so they must not log to the same logger.
+// For sync. logger, goroutines logging at the same time run hooks
+// concurrently, so hooks must be safe for concurrent use.
+//
+// Hooks see records which passed the levels of the logger, and
+// a changed Level isn't checked against them again: a raised Level
+// doesn't bring back filtered records, and the stack trace is attached
+// by the Level before hooks. Adapters check their own Level as usual.
+type Hook func(r *Record) bool
+
+// AddHook for singleton
+func AddHook(h Hook) { lgr.AddHook(h) }
+
+// AddHook appends a hook which runs after already added hooks.
+func (logger *Logger) AddHook(h Hook) {
+	if h == nil {
+		return
+	}
+
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	// copy on write, hooks are read without lock by asyncProc
+	hooks := logger.loadHooks()
+	newHooks := make([]Hook, len(hooks), len(hooks)+1)
+	copy(newHooks, hooks)
+	logger.hooks.Store(append(newHooks, h))
+}
+
+// ClearHooks for singleton
+func ClearHooks() { lgr.ClearHooks() }
+
+// ClearHooks removes all hooks.
+func (logger *Logger) ClearHooks() {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	logger.hooks.Store([]Hook(nil))
+}
+
+func (logger *Logger) loadHooks() []Hook {
+	hooks, _ := logger.hooks.Load().([]Hook)
+	return hooks
+}
+
+// runHooks reports whether the record survives all hooks.
+func runHooks(hooks []Hook, r *Record) bool {
+	for _, h := range hooks {
+		if !h(r) {
+			return false
+		}
+	}
+	return true
+}
+
+///////////////////////////////////////////////////////////////////////
+// hooks
+///////////////////////////////////////////////////////////////////////
+
+// StaticFields returns a hook which appends fields to every record,
+// e.g. service name, version and region.
+func StaticFields(fields ...Field) Hook {
+	fs := make([]Field, len(fields))
+	copy(fs, fields)
+	return func(r *Record) bool {
+		// full slice expression to never write into caller's array
+		r.Fields = append(r.Fields[:len(r.Fields):len(r.Fields)], fs...)
+		return true
+	}
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStaticFields(t *testing.T) {
+	h := StaticFields(F("service", "api"), F("version", "1.2"))
+
+	given := make([]Field, 1, 4)
+	given[0] = F("id", 7)
+	r := &Record{Fields: given}
+	if !h(r) {
+		t.Fatal("static fields hook dropped a record")
+	}
+
+	if len(r.Fields) != 3 || r.Fields[1].Key != "service" || r.Fields[2].Value != "1.2" {
+		t.Errorf("unexpected fields: %+v", r.Fields)
+	}
+	if given[:2][1].Key == "service" {
+		t.Error("hook wrote into caller's array")
+	}
+}
+
+func TestHooks(t *testing.T) {
+	for _, async := range []bool{false, true} {
+		filename := filepath.Join(t.TempDir(), "hook.log")
+
+		l := New("hook", async)
+		c := NewFileAdapterConfig()
+		c.Filename = filename
+		c.Format = "$msg$fields"
+		if err := l.Attach(c); err != nil {
+			t.Fatal(err)
+		}
+
+		var order []int
+		l.AddHook(func(r *Record) bool {
+			order = append(order, 1)
+			return r.Msg != "secret"
+		})
+		l.AddHook(func(r *Record) bool {
+			order = append(order, 2)
+			if s, ok := r.Msg.(string); ok {
+				r.Msg = strings.ToUpper(s)
+			}
+			return true
+		})
+		l.AddHook(StaticFields(F("region", "eu")))
+
+		l.Information("hello")
+		l.Information("secret")
+		l.Flush()
+		l.Detach(AdapterFile)
+
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(b), "HELLO region=eu"+lineFeed; got != want {
+			t.Errorf("async %v: got %q, want %q", async, got, want)
+		}
+		if len(order) != 3 || order[0] != 1 || order[1] != 2 || order[2] != 1 {
+			t.Errorf("async %v: unexpected hook order %v", async, order)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package logger
+
+// Valuer is a deferred log message or field value.
+// LogValue is called only when the record is enabled,
+// on the logging goroutine even for async. logger.
+type Valuer interface {
+	LogValue() interface{}
+}
+
+// Lazy is a function which builds a log message or field value on demand,
+// e.g. logger.Debug(logger.Lazy(func() interface{} { return dump(state) })).
+// A plain func() interface{} isn't called, it's logged as a value.
+type Lazy func() interface{}
+
+// LogValue calls f.
+func (f Lazy) LogValue() interface{} {
+	return f()
+}
+
+func deferred(o interface{}) bool {
+	_, ok := o.(Valuer)
+	return ok
+}
+
+// resolve evaluates a deferred value.
+func resolve(o interface{}) interface{} {
+	if v, ok := o.(Valuer); ok {
+		return v.LogValue()
+	}
+	return o
+}
+
+// resolveFields evaluates deferred field values,
+// copying fields not to change caller's array.
+func resolveFields(fields []Field) []Field {
+	copied := false
+	for i, f := range fields {
+		if !deferred(f.Value) {
+			continue
+		}
+
+		if !copied {
+			fields = append([]Field(nil), fields...)
+			copied = true
+		}
+		fields[i].Value = resolve(f.Value)
+	}
+	return fields
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestLazy(t *testing.T) {
+	var out bytes.Buffer
+
+	l := New("lazy", false)
+	if l.Enabled(LevelFatal) {
+		t.Error("logger without adapters should be disabled")
+	}
+
+	c := NewConsoleAdapterConfig()
+	c.Level = LevelInformation
+	c.Format = "$msg$fields"
+	c.Color = false
+	c.Writer = &out
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if l.Enabled(LevelDebug) || !l.Enabled(LevelInformation) {
+		t.Error("Enabled doesn't follow adapter level")
+	}
+
+	calls := 0
+	expensive := func() interface{} {
+		calls++
+		return "built"
+	}
+
+	l.Debug(Lazy(expensive))
+	l.Debug("skipped", F("v", Lazy(expensive)))
+	if calls != 0 {
+		t.Errorf("disabled record evaluated %d times", calls)
+	}
+
+	l.Information(Lazy(expensive), F("v", Lazy(expensive)), F("ids", []int{1}))
+	if got, want := out.String(), "built v=built ids=[1]"+lineFeed; got != want || calls != 2 {
+		t.Errorf("got %q (%d calls), want %q", got, calls, want)
+	}
+
+	l.SetLevel(LevelError)
+	if l.Enabled(LevelWarning) {
+		t.Error("Enabled doesn't follow logger level")
+	}
+}
+
+func TestLoggingWhileChanging(t *testing.T) {
+	l := New("race", false)
+	c := NewConsoleAdapterConfig()
+	c.Writer = io.Discard
+	c.Format = "$goroutine $msg"
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					l.Information("record", F("k", 1))
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		l.Attach(c)
+		l.SetStackLevel(LevelInformation)
+		l.SetCallerLookup(i%2 == 0)
+		l.ClearStackLevel()
+		l.Detach(AdapterConsole)
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Level is log level
+type Level int
+
+// log levels
+const (
+	LevelDebug Level = iota
+	LevelVerbose
+	LevelInformation
+	LevelWarning
+	LevelError
+	LevelPanic
+	LevelFatal
+)
+
+// additional log levels
+// Their values are out of the range of basic levels to keep basic levels stable,
+// and they are placed among basic levels by their severities.
+// Trace is below the default level Debug of loggers and adapters,
+// so it's written only when both levels are set to LevelTrace.
+const (
+	LevelTrace  Level = -1
+	LevelNotice Level = 7
+)
+
+// ErrLevelExist is returned when registering a level whose value or name is already used.
+var ErrLevelExist = errors.New("level already exist")
+
+// LevelSpec describes a log level.
+type LevelSpec struct {
+	Name     string // long name such as "information"
+	Short    string // short code such as "INF"
+	Severity int    // higher is more severe
+	Color    string // ANSI escape sequence for console adapter
+}
+
+// Severities of basic levels are 10 times their values,
+// so that new levels can be placed among them.
+var basicLevels = map[Level]LevelSpec{
+	LevelTrace:       {Name: "trace", Short: "TRC", Severity: -10, Color: prefixDGray},
+	LevelDebug:       {Name: "debug", Short: "DBG", Severity: 0, Color: prefixCyan},
+	LevelVerbose:     {Name: "verbose", Short: "VBS", Severity: 10, Color: ""},
+	LevelInformation: {Name: "information", Short: "INF", Severity: 20, Color: prefixLGreen},
+	LevelNotice:      {Name: "notice", Short: "NTC", Severity: 25, Color: prefixLBlue},
+	LevelWarning:     {Name: "warning", Short: "WRN", Severity: 30, Color: prefixLYellow},
+	LevelError:       {Name: "error", Short: "ERR", Severity: 40, Color: prefixLRed},
+	LevelPanic:       {Name: "panic", Short: "PNC", Severity: 50, Color: prefixBPurble},
+	LevelFatal:       {Name: "fatal", Short: "FTL", Severity: 60, Color: prefixBRed},
+}
+
+var (
+	levelLock  sync.Mutex   // serializes RegisterLevel
+	levelTable atomic.Value // map[Level]LevelSpec, copied on write
+)
+
+func levelSpecs() map[Level]LevelSpec {
+	if t, ok := levelTable.Load().(map[Level]LevelSpec); ok {
+		return t
+	}
+	return basicLevels
+}
+
+// RegisterLevel adds a user-defined level.
+// Basic and already registered levels can't be redefined.
+func RegisterLevel(l Level, spec LevelSpec) error {
+	if len(spec.Name) == 0 || len(spec.Short) == 0 {
+		return ErrInvalidLevel
+	}
+
+	levelLock.Lock()
+	defer levelLock.Unlock()
+
+	specs := levelSpecs()
+	if _, ok := specs[l]; ok {
+		return ErrLevelExist
+	}
+	for _, s := range specs {
+		if strings.EqualFold(s.Name, spec.Name) || strings.EqualFold(s.Short, spec.Short) {
+			return ErrLevelExist
+		}
+	}
+
+	table := make(map[Level]LevelSpec, len(specs)+1)
+	for k, v := range specs {
+		table[k] = v
+	}
+	table[l] = spec
+	levelTable.Store(table)
+	return nil
+}
+
+// LookupLevel returns the spec of a level.
+func LookupLevel(l Level) (LevelSpec, bool) {
+	spec, ok := levelSpecs()[l]
+	return spec, ok
+}
+
+// Levels returns all known levels ordered by severity.
+func Levels() []Level {
+	specs := levelSpecs()
+	levels := make([]Level, 0, len(specs))
+	for l := range specs {
+		levels = append(levels, l)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return specs[levels[i]].Severity < specs[levels[j]].Severity
+	})
+	return levels
+}
+
+// Str2Level convert string to Level
+// str is either long name or short code of a level, case insensitive.
+// Unknown string is converted to LevelDebug.
+func Str2Level(str string) (l Level) {
+	for level, spec := range levelSpecs() {
+		if strings.EqualFold(str, spec.Name) || strings.EqualFold(str, spec.Short) {
+			return level
+		}
+	}
+	return LevelDebug
+}
+
+// String returns short code of the level.
+func (l Level) String() string {
+	if spec, ok := levelSpecs()[l]; ok {
+		return spec.Short
+	}
+	return "L" + strconv.Itoa(int(l))
+}
+
+func (l Level) valid() bool {
+	_, ok := levelSpecs()[l]
+	return ok
+}
+
+func (l Level) severity() int {
+	if spec, ok := levelSpecs()[l]; ok {
+		return spec.Severity
+	}
+	return int(l) * 10
+}
+
+// atLeast reports whether l is as severe as min or more.
+func (l Level) atLeast(min Level) bool {
+	return l.severity() >= min.severity()
+}
@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLevelOrder(t *testing.T) {
+	order := []Level{LevelTrace, LevelDebug, LevelInformation, LevelNotice, LevelWarning, LevelError, LevelPanic, LevelFatal}
+	for i := 1; i < len(order); i++ {
+		if !order[i].atLeast(order[i-1]) || order[i-1].atLeast(order[i]) {
+			t.Errorf("%v should be more severe than %v", order[i], order[i-1])
+		}
+	}
+}
+
+// restoreLevels brings back the level table when t ends,
+// so that levels registered by t don't leak to other tests.
+func restoreLevels(t *testing.T) {
+	specs := levelSpecs()
+	t.Cleanup(func() {
+		levelLock.Lock()
+		defer levelLock.Unlock()
+		levelTable.Store(specs)
+	})
+}
+
+func TestRegisterLevel(t *testing.T) {
+	restoreLevels(t)
+
+	const levelAudit Level = 100
+	spec := LevelSpec{Name: "audit", Short: "AUD", Severity: 35}
+	if err := RegisterLevel(levelAudit, spec); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterLevel(levelAudit, spec); err != ErrLevelExist {
+		t.Errorf("got %v, want ErrLevelExist", err)
+	}
+	if err := RegisterLevel(LevelWarning, LevelSpec{Name: "warn2", Short: "WR2"}); err != ErrLevelExist {
+		t.Errorf("got %v, want ErrLevelExist", err)
+	}
+
+	if l := Str2Level("aud"); l != levelAudit {
+		t.Errorf("Str2Level: got %v", l)
+	}
+	if s := levelAudit.String(); s != "AUD" {
+		t.Errorf("String: got %q", s)
+	}
+	if !levelAudit.atLeast(LevelWarning) || levelAudit.atLeast(LevelError) {
+		t.Error("audit should be placed between warning and error")
+	}
+}
+
+func TestTraceNotice(t *testing.T) {
+	var out bytes.Buffer
+	l := New("app", false)
+	c := NewConsoleAdapterConfig()
+	c.Format = "$slevel $msg"
+	c.Color = false
+	c.Writer = &out
+	c.Level = LevelTrace
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+
+	// filtered by the default level Debug
+	l.Trace("hidden")
+	l.Notice("notice")
+	l.SetLevel(LevelTrace)
+	l.Tracef("trace %d", 1)
+	l.Noticef("notice %d", 2)
+
+	want := "NTC notice" + lineFeed + "TRC trace 1" + lineFeed + "NTC notice 2" + lineFeed
+	if got := out.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
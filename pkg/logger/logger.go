@@ -3,14 +3,12 @@ package logger
 import (
 	"errors"
 	"fmt"
-	"io"
 	"math"
 	"os"
 	"path"
 	"runtime"
-	"sort"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,8 +20,13 @@ import (
 // v0.3: add truncation mode to file adapter
 // v0.4: add log date/time format (short and long)
 // v0.5: singleton, enhance thread safety
+// v0.6: format tokenizer, time layouts and user-defined levels
+// v0.7: structured fields, JSON encoder, stack policies, hooks, redaction and filters
+// v0.8: console writers, reopen, error handler with fallback, durability and background flush
+// v0.9: lock-free level checks, pooled encoder, named loggers, reader and metrics
+// v0.10: multi-line policy, caller skip, timer, audit, encryption and journald adapters
 
-const version = "0.5.8"
+const version = "0.10.0"
 
 // GetVersion returns version string.
 func GetVersion() string {
@@ -60,17 +63,41 @@ func Substitute(l *Logger) {
 // Logging Functions
 ///////////////////////////////////////////////////////////////////////
 
+// Trace for singleton
+func Trace(obj interface{}, fields ...Field) { lgr.Trace(obj, fields...) }
+
+// Trace outputs "trace" level normal string log.
+func (logger *Logger) Trace(obj interface{}, fields ...Field) {
+	if !logger.enabled(LevelTrace) {
+		return
+	}
+
+	logger.write(LevelTrace, obj, fields)
+}
+
+// Tracef for singleton
+func Tracef(format string, arg ...interface{}) { lgr.Tracef(format, arg...) }
+
+// Tracef outputs "trace" level formatted string log.
+func (logger *Logger) Tracef(format string, arg ...interface{}) {
+	if !logger.enabled(LevelTrace) || len(format) == 0 {
+		return
+	}
+
+	log := fmt.Sprintf(format, arg...)
+	logger.write(LevelTrace, log, nil)
+}
+
 // Debug for singleton
-func Debug(obj interface{}) { lgr.Debug(obj) }
+func Debug(obj interface{}, fields ...Field) { lgr.Debug(obj, fields...) }
 
 // Debug outputs "debug" level normal string log.
-func (logger *Logger) Debug(obj interface{}) {
-	logger.lock.RLock()
-	defer logger.lock.RUnlock()
-
-	if LevelDebug >= logger.level {
-		logger.write(LevelDebug, obj)
+func (logger *Logger) Debug(obj interface{}, fields ...Field) {
+	if !logger.enabled(LevelDebug) {
+		return
 	}
+
+	logger.write(LevelDebug, obj, fields)
 }
 
 // Debugf for singleton
@@ -78,26 +105,24 @@ func Debugf(format string, arg ...interface{}) { lgr.Debugf(format, arg...) }
 
 // Debugf outputs "debug" level formatted string log.
 func (logger *Logger) Debugf(format string, arg ...interface{}) {
-	logger.lock.RLock()
-	defer logger.lock.RUnlock()
-
-	if LevelDebug >= logger.level && len(format) > 0 {
-		log := fmt.Sprintf(format, arg...)
-		logger.write(LevelDebug, log)
+	if !logger.enabled(LevelDebug) || len(format) == 0 {
+		return
 	}
+
+	log := fmt.Sprintf(format, arg...)
+	logger.write(LevelDebug, log, nil)
 }
 
 // Verbose for singleton
-func Verbose(obj interface{}) { lgr.Verbose(obj) }
+func Verbose(obj interface{}, fields ...Field) { lgr.Verbose(obj, fields...) }
 
 // Verbose outputs "verbose" level normal string log.
-func (logger *Logger) Verbose(obj interface{}) {
-	logger.lock.RLock()
-	defer logger.lock.RUnlock()
-
-	if LevelVerbose >= logger.level {
-		logger.write(LevelVerbose, obj)
+func (logger *Logger) Verbose(obj interface{}, fields ...Field) {
+	if !logger.enabled(LevelVerbose) {
+		return
 	}
+
+	logger.write(LevelVerbose, obj, fields)
 }
 
 // Verbosef for singleton
@@ -105,26 +130,24 @@ func Verbosef(format string, arg ...interface{}) { lgr.Verbosef(format, arg...)
 
 // Verbosef outputs "verbose" level formatted string log.
 func (logger *Logger) Verbosef(format string, arg ...interface{}) {
-	logger.lock.RLock()
-	defer logger.lock.RUnlock()
-
-	if LevelVerbose >= logger.level && len(format) > 0 {
-		log := fmt.Sprintf(format, arg...)
-		logger.write(LevelVerbose, log)
+	if !logger.enabled(LevelVerbose) || len(format) == 0 {
+		return
 	}
+
+	log := fmt.Sprintf(format, arg...)
+	logger.write(LevelVerbose, log, nil)
 }
 
 // Information for singleton
-func Information(obj interface{}) { lgr.Information(obj) }
+func Information(obj interface{}, fields ...Field) { lgr.Information(obj, fields...) }
 
 // Information outputs "information" level normal string log.
-func (logger *Logger) Information(obj interface{}) {
-	logger.lock.RLock()
-	defer logger.lock.RUnlock()
-
-	if LevelInformation >= logger.level {
-		logger.write(LevelInformation, obj)
+func (logger *Logger) Information(obj interface{}, fields ...Field) {
+	if !logger.enabled(LevelInformation) {
+		return
 	}
+
+	logger.write(LevelInformation, obj, fields)
 }
 
 // Informationf for singleton
@@ -132,26 +155,49 @@ func Informationf(format string, arg ...interface{}) { lgr.Informationf(format,
 
 // Informationf outputs "information" level formatted string log.
 func (logger *Logger) Informationf(format string, arg ...interface{}) {
-	logger.lock.RLock()
-	defer logger.lock.RUnlock()
+	if !logger.enabled(LevelInformation) || len(format) == 0 {
+		return
+	}
 
-	if LevelInformation >= logger.level && len(format) > 0 {
-		log := fmt.Sprintf(format, arg...)
-		logger.write(LevelInformation, log)
+	log := fmt.Sprintf(format, arg...)
+	logger.write(LevelInformation, log, nil)
+}
+
+// Notice for singleton
+func Notice(obj interface{}, fields ...Field) { lgr.Notice(obj, fields...) }
+
+// Notice outputs "notice" level normal string log.
+func (logger *Logger) Notice(obj interface{}, fields ...Field) {
+	if !logger.enabled(LevelNotice) {
+		return
+	}
+
+	logger.write(LevelNotice, obj, fields)
+}
+
+// Noticef for singleton
+func Noticef(format string, arg ...interface{}) { lgr.Noticef(format, arg...) }
+
+// Noticef outputs "notice" level formatted string log.
+func (logger *Logger) Noticef(format string, arg ...interface{}) {
+	if !logger.enabled(LevelNotice) || len(format) == 0 {
+		return
 	}
+
+	log := fmt.Sprintf(format, arg...)
+	logger.write(LevelNotice, log, nil)
 }
 
 // Warning for singleton
-func Warning(obj interface{}) { lgr.Warning(obj) }
+func Warning(obj interface{}, fields ...Field) { lgr.Warning(obj, fields...) }
 
 // Warning outputs "warninig" level normal string log.
-func (logger *Logger) Warning(obj interface{}) {
-	logger.lock.RLock()
-	defer logger.lock.RUnlock()
-
-	if LevelWarning >= logger.level {
-		logger.write(LevelWarning, obj)
+func (logger *Logger) Warning(obj interface{}, fields ...Field) {
+	if !logger.enabled(LevelWarning) {
+		return
 	}
+
+	logger.write(LevelWarning, obj, fields)
 }
 
 // Warningf for singleton
@@ -159,26 +205,24 @@ func Warningf(format string, arg ...interface{}) { lgr.Warningf(format, arg...)
 
 // Warningf outputs "warning" level formatted string log.
 func (logger *Logger) Warningf(format string, arg ...interface{}) {
-	logger.lock.RLock()
-	defer logger.lock.RUnlock()
-
-	if LevelWarning >= logger.level && len(format) > 0 {
-		log := fmt.Sprintf(format, arg...)
-		logger.write(LevelWarning, log)
+	if !logger.enabled(LevelWarning) || len(format) == 0 {
+		return
 	}
+
+	log := fmt.Sprintf(format, arg...)
+	logger.write(LevelWarning, log, nil)
 }
 
 // Error for singleton
-func Error(obj interface{}) { lgr.Error(obj) }
+func Error(obj interface{}, fields ...Field) { lgr.Error(obj, fields...) }
 
 // Error outputs "error" level normal string log.
-func (logger *Logger) Error(obj interface{}) {
-	logger.lock.RLock()
-	defer logger.lock.RUnlock()
-
-	if LevelError >= logger.level {
-		logger.write(LevelError, obj)
+func (logger *Logger) Error(obj interface{}, fields ...Field) {
+	if !logger.enabled(LevelError) {
+		return
 	}
+
+	logger.write(LevelError, obj, fields)
 }
 
 // Errorf for singleton
@@ -186,27 +230,23 @@ func Errorf(format string, arg ...interface{}) { lgr.Errorf(format, arg...) }
 
 // Errorf outputs "error" level formatted string log.
 func (logger *Logger) Errorf(format string, arg ...interface{}) {
-	logger.lock.RLock()
-	defer logger.lock.RUnlock()
-
-	if LevelError >= logger.level && len(format) > 0 {
-		log := fmt.Sprintf(format, arg...)
-		logger.write(LevelError, log)
+	if !logger.enabled(LevelError) || len(format) == 0 {
+		return
 	}
+
+	log := fmt.Sprintf(format, arg...)
+	logger.write(LevelError, log, nil)
 }
 
 // Panic for singleton
-func Panic(obj interface{}) { lgr.Panic(obj) }
+func Panic(obj interface{}, fields ...Field) { lgr.Panic(obj, fields...) }
 
 // Panic outputs "panic" level normal string log
 // when the logger's level is set to less equal LevelPanic
 // and is follwed by a call to panic(log).
-func (logger *Logger) Panic(obj interface{}) {
-	logger.lock.RLock()
-	defer logger.lock.RUnlock()
-
-	if LevelPanic >= logger.level {
-		logger.write(LevelPanic, obj)
+func (logger *Logger) Panic(obj interface{}, fields ...Field) {
+	if logger.enabled(LevelPanic) {
+		logger.write(LevelPanic, obj, fields)
 	}
 	logger.Flush()
 	panic(obj)
@@ -219,27 +259,21 @@ func Panicf(format string, arg ...interface{}) { lgr.Panicf(format, arg...) }
 // when the logger's level is set to less equal LevelPanic
 // and is followed by a call to panic(...).
 func (logger *Logger) Panicf(format string, arg ...interface{}) {
-	logger.lock.RLock()
-	defer logger.lock.RUnlock()
-
 	log := fmt.Sprintf(format, arg...)
-	if LevelPanic >= logger.level && len(format) > 0 {
-		logger.write(LevelPanic, log)
+	if logger.enabled(LevelPanic) && len(format) > 0 {
+		logger.write(LevelPanic, log, nil)
 	}
 	logger.Flush()
 	panic(log)
 }
 
 // Fatal for singleton
-func Fatal(obj interface{}) { lgr.Fatal(obj) }
+func Fatal(obj interface{}, fields ...Field) { lgr.Fatal(obj, fields...) }
 
 // Fatal outputs "fatal" level normal string log
 // and is followed by a call to os.Exit(1).
-func (logger *Logger) Fatal(obj interface{}) {
-	logger.lock.RLock()
-	defer logger.lock.RUnlock()
-
-	logger.write(LevelFatal, obj)
+func (logger *Logger) Fatal(obj interface{}, fields ...Field) {
+	logger.write(LevelFatal, obj, fields)
 	logger.Flush()
 	os.Exit(1)
 }
@@ -250,37 +284,66 @@ func Fatalf(format string, arg ...interface{}) { lgr.Fatalf(format, arg...) }
 // Fatalf outputs "fatal" level formatted string log
 // and is followed by a call to os.Exit(1).
 func (logger *Logger) Fatalf(format string, arg ...interface{}) {
-	logger.lock.RLock()
-	defer logger.lock.RUnlock()
-
 	if len(format) > 0 {
 		log := fmt.Sprintf(format, arg...)
-		logger.write(LevelFatal, log)
+		logger.write(LevelFatal, log, nil)
 	}
 	logger.Flush()
 	os.Exit(1)
 }
 
+// Log for singleton
+func Log(l Level, obj interface{}, fields ...Field) { lgr.Log(l, obj, fields...) }
+
+// Log outputs normal string log of given level.
+// Unlike Panic and Fatal, it doesn't panic nor exit at LevelPanic and LevelFatal.
+func (logger *Logger) Log(l Level, obj interface{}, fields ...Field) {
+	if !logger.enabled(l) {
+		return
+	}
+
+	logger.write(l, obj, fields)
+}
+
+// Logf for singleton
+func Logf(l Level, format string, arg ...interface{}) { lgr.Logf(l, format, arg...) }
+
+// Logf outputs formatted string log of given level.
+// Unlike Panicf and Fatalf, it doesn't panic nor exit at LevelPanic and LevelFatal.
+func (logger *Logger) Logf(l Level, format string, arg ...interface{}) {
+	if !logger.enabled(l) || len(format) == 0 {
+		return
+	}
+
+	log := fmt.Sprintf(format, arg...)
+	logger.write(l, log, nil)
+}
+
 // Stack for singleton
 func Stack(l Level, bufLen int) { lgr.Stack(l, bufLen) }
 
 // Stack outputs current goroutines's execution stack.
+// bufLen is initial buffer length, the buffer grows until the whole stack fits.
 func (logger *Logger) Stack(l Level, bufLen int) {
-	logger.lock.RLock()
-	defer logger.lock.RUnlock()
-
-	if l < LevelDebug || l > LevelFatal {
+	if !l.valid() {
 		return
 	}
 
-	// minimum 1024 bytes
-	if bufLen < 1024 {
-		bufLen = 1024
+	logger.write(l, string(dumpStack(bufLen, false)), nil)
+}
+
+// StackAll for singleton
+func StackAll(l Level) { lgr.StackAll(l) }
+
+// StackAll outputs execution stacks of all goroutines.
+// The buffer starts at a size estimated by the number of goroutines
+// and grows until the whole dump fits.
+func (logger *Logger) StackAll(l Level) {
+	if !l.valid() {
+		return
 	}
 
-	buf := make([]byte, bufLen)
-	len := runtime.Stack(buf, false)
-	logger.write(l, string(buf[:len]))
+	logger.write(l, string(dumpStack(runtime.NumGoroutine()*goroutineDumpLen, true)), nil)
 }
 
 // Flush for singleton
@@ -303,6 +366,7 @@ var (
 	ErrAlreadyExist  = errors.New("adapter already exist")
 	ErrInvalidConfig = errors.New("invalid config")
 	ErrInvalidLevel  = errors.New("invalid level")
+	ErrInvalidFormat = errors.New("invalid format")
 )
 
 // AdapterID is log adapter ID
@@ -312,66 +376,88 @@ type AdapterID int
 const (
 	AdapterConsole AdapterID = iota
 	AdapterFile
+	AdapterAudit
+	AdapterJournald
 )
 
-// Level is log level
-type Level int
+// DefaultFormat is default log string format.
+//
+// Format tokens:
+//
+//	$name: logger name
+//	$ltime: "2006-01-02 15:04:05.000"
+//	$stime: "15:04:05.000"
+//	$ts: unix time in microseconds
+//	${time:LAYOUT}: LAYOUT is a layout name such as RFC3339Nano or a Go time layout
+//	${unix:UNIT}: unix time, UNIT is one of s(default), ms, us and ns
+//	$ilevel, $slevel: level number and level string
+//	$function, $line: caller function and line
+//	$file, $pkgfile, $path: caller file name, package path and file name
+//	  (e.g. "example.com/app/db/conn.go") and full path of the file
+//	$msg: log message
+//	$fields: fields of the record as " key=value" each
+//	$pid, $hostname, $goroutine: process ID, host name and goroutine ID
+//
+// Any token can be written as ${token} and padded as ${token:WIDTH}
+// (negative WIDTH aligns left, e.g. ${slevel:-5}). $$ is a literal '$'.
+// A dangling '$', an unterminated '${' and unknown tokens are errors,
+// Attach fails with ErrInvalidFormat.
+// Time tokens are rendered in the time zone of the adapter (TimeZone).
+const DefaultFormat = "$ltime [$slevel] $msg$fields ($file:$line)"
 
-// log levels
-const (
-	LevelDebug Level = iota
-	LevelVerbose
-	LevelInformation
-	LevelWarning
-	LevelError
-	LevelPanic
-	LevelFatal
-)
+// Logger structure
+// A logger made by New owns its adapters and settings,
+// which are shared by its children made by Named and With.
+type Logger struct {
+	*core
+	name       string
+	fields     []Field // prepended to fields of every record
+	callerSkip int     // frames skipped in addition to logging functions
+}
 
-var levelString = []string{
-	"DBG",
-	"VBS",
-	"INF",
-	"WRN",
-	"ERR",
-	"PNC",
-	"FTL",
-}
-
-// Str2Level convert string to Level
-func Str2Level(str string) (l Level) {
-	lower := strings.ToLower(str)
-	switch lower {
-	case "verbose", "vbs":
-		l = LevelVerbose
-	case "information", "inf":
-		l = LevelInformation
-	case "warning", "wrn":
-		l = LevelWarning
-	case "error", "err":
-		l = LevelError
-	case "panic", "pnc":
-		l = LevelPanic
-	case "fatal", "ftl":
-		l = LevelFatal
-	default:
-		l = LevelDebug
-	}
-	return
+// core is state shared by a logger and its children.
+type core struct {
+	root      string // name of the logger made by New
+	counters  *counters
+	lock      sync.RWMutex // serializes changes, logging functions don't take it
+	adapters  atomic.Value // []adapter, copied on write
+	settings  atomic.Value // *settings, copied on write
+	threshold int32        // minimum severity accepted by any logger and adapters, atomic
+	levels    atomic.Value // nameLevels
+	hooks     atomic.Value // []Hook
+	redactor  atomic.Value // *redactor
+	onError   atomic.Value // ErrorHandler
+	fallback  atomic.Value // *fallbackAdapter
+	async     bool
+	msgChan   chan *message
+	wait      sync.WaitGroup
 }
 
-// DefaultFormat is default log string format.
-const DefaultFormat = "$ltime [$slevel] $msg ($file:$line)"
+// settings are read by logging functions without the lock.
+type settings struct {
+	captures capture
+	noCaller bool
+	errStack bool
+	stackOn  bool
+	stackMin Level
+}
 
-// Logger structure
-type Logger struct {
-	name     string
-	level    Level
-	lock     sync.RWMutex
-	adapters []adapter
-	async    bool
-	msgChan  chan *message
-	wait     sync.WaitGroup
+func (logger *Logger) loadSettings() *settings {
+	s, _ := logger.settings.Load().(*settings)
+	return s
+}
+
+// updateSettings changes a copy of settings by f and stores it,
+// with the lock held.
+func (logger *Logger) updateSettings(f func(s *settings)) {
+	s := *logger.loadSettings()
+	f(&s)
+	logger.settings.Store(&s)
+}
+
+func (logger *Logger) loadAdapters() []adapter {
+	adapters, _ := logger.adapters.Load().([]adapter)
+	return adapters
 }
 
 // AdapterConfig is an interface of configuration for a log output.
@@ -383,19 +469,25 @@ type adapter interface {
 	id() AdapterID
 	init(c AdapterConfig) error
 	uninit()
-	write(m *message)
-	flush()
+	write(m *message) (wrote bool, err error)
+	flush() error
+	captures() capture
+	level() Level
 }
 
 // New makes a new Logger instance.
 func New(name string, async bool) (logger *Logger) {
 	logger = &Logger{
-		name:  name,
-		level: LevelDebug,
+		core: &core{counters: &counters{}},
+		name: name,
 	}
 	if len(name) == 0 {
 		logger.name = "No Name"
 	}
+	logger.root = logger.name
+	logger.levels.Store(nameLevels{logger.root: LevelDebug})
+	logger.settings.Store(&settings{})
+	logger.updateAdapters()
 
 	if async {
 		// use core count for channel buffer
@@ -419,27 +511,67 @@ func (logger *Logger) Attach(config AdapterConfig) error {
 	logger.lock.Lock()
 	defer logger.lock.Unlock()
 
-	for _, a := range logger.adapters {
+	adapters := logger.loadAdapters()
+	for _, a := range adapters {
 		if a.id() == config.id() {
 			return ErrAlreadyExist
 		}
 	}
 
+	ad, err := newAdapter(config)
+	if err != nil {
+		return err
+	}
+
+	newAdapters := make([]adapter, len(adapters), len(adapters)+1)
+	copy(newAdapters, adapters)
+	logger.adapters.Store(append(newAdapters, ad))
+	logger.updateAdapters()
+	return nil
+}
+
+func newAdapter(config AdapterConfig) (adapter, error) {
 	var ctor func() adapter
 	switch config.id() {
 	case AdapterConsole:
 		ctor = newConsoleAdapter
 	case AdapterFile:
 		ctor = newFileAdapter
+	case AdapterAudit:
+		ctor = newAuditAdapter
+	case AdapterJournald:
+		ctor = newJournaldAdapter
+	default:
+		return nil, ErrInvalidConfig
 	}
 
 	ad := ctor()
 	if err := ad.init(config); err != nil {
-		return err
+		return nil, err
 	}
+	return ad, nil
+}
 
-	logger.adapters = append(logger.adapters, ad)
-	return nil
+// updateAdapters collects data required by adapters
+// and caches the minimum severity accepted by loggers and adapters.
+func (logger *Logger) updateAdapters() {
+	var captures capture
+	threshold := int32(math.MaxInt32)
+	for _, a := range logger.loadAdapters() {
+		captures |= a.captures()
+		if s := int32(a.level().severity()); s < threshold {
+			threshold = s
+		}
+	}
+	if fb := logger.loadFallback(); fb != nil {
+		captures |= fb.a.captures()
+	}
+	if s := int32(logger.loadLevels().min(logger.root).severity()); s > threshold {
+		threshold = s
+	}
+
+	logger.updateSettings(func(s *settings) { s.captures = captures })
+	atomic.StoreInt32(&logger.threshold, threshold)
 }
 
 // Detach for singleton
@@ -447,7 +579,7 @@ func Detach(id AdapterID) { lgr.Detach(id) }
 
 // Detach detaches an output adapter.
 func (logger *Logger) Detach(id AdapterID) {
-	if id < AdapterConsole || id > AdapterFile {
+	if id < AdapterConsole || id > AdapterJournald {
 		return
 	}
 
@@ -456,50 +588,120 @@ func (logger *Logger) Detach(id AdapterID) {
 
 	logger.flush()
 
-	var adapters []adapter
-	for _, a := range logger.adapters {
+	// records being written to the detached adapter are dropped
+	var adapters, detached []adapter
+	for _, a := range logger.loadAdapters() {
 		if a.id() == id {
-			a.uninit()
+			detached = append(detached, a)
 			continue
 		}
 		adapters = append(adapters, a)
 	}
-	logger.adapters = adapters
+	logger.adapters.Store(adapters)
+	logger.updateAdapters()
+	for _, a := range detached {
+		a.uninit()
+	}
 }
 
 // SetLevel for singleton
 func SetLevel(l Level) error { return lgr.SetLevel(l) }
 
 // SetLevel sets level of logger.
+// It also applies to the children of the logger
+// unless their levels are set, see SetNameLevel.
 func (logger *Logger) SetLevel(l Level) error {
-	if l < LevelDebug || l > LevelFatal {
+	return logger.SetNameLevel(logger.name, l)
+}
+
+// Enabled for singleton
+func Enabled(l Level) bool { return lgr.Enabled(l) }
+
+// Enabled reports whether a record of level l would be written,
+// i.e. l is above the logger's level and at least one adapter's level.
+// It doesn't take the lock.
+func (logger *Logger) Enabled(l Level) bool {
+	return l.valid() && int32(l.severity()) >= atomic.LoadInt32(&logger.threshold) &&
+		l.atLeast(logger.loadLevels().resolve(logger.name))
+}
+
+// SetErrorStack for singleton
+func SetErrorStack(enable bool) { lgr.SetErrorStack(enable) }
+
+// SetErrorStack enables capturing stack trace at logging site
+// for records of LevelError and above which carry an error
+// as a message or a field.
+func (logger *Logger) SetErrorStack(enable bool) {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+	logger.updateSettings(func(s *settings) { s.errStack = enable })
+}
+
+// SetStackLevel for singleton
+func SetStackLevel(l Level) error { return lgr.SetStackLevel(l) }
+
+// SetStackLevel attaches stack trace of logging site
+// to every record at level l and above.
+func (logger *Logger) SetStackLevel(l Level) error {
+	if !l.valid() {
 		return ErrInvalidLevel
 	}
 	logger.lock.Lock()
 	defer logger.lock.Unlock()
-	logger.level = l
+	logger.updateSettings(func(s *settings) {
+		s.stackOn = true
+		s.stackMin = l
+	})
 	return nil
 }
 
+// ClearStackLevel for singleton
+func ClearStackLevel() { lgr.ClearStackLevel() }
+
+// ClearStackLevel stops attaching stack trace set by SetStackLevel.
+func (logger *Logger) ClearStackLevel() {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+	logger.updateSettings(func(s *settings) { s.stackOn = false })
+}
+
 ///////////////////////////////////////////////////////////////////////
 
 func (logger *Logger) flush() {
 	if logger.async {
 		logger.wait.Wait()
 	}
-	for _, a := range logger.adapters {
-		a.flush()
+	for _, a := range logger.loadAdapters() {
+		if err := a.flush(); err != nil {
+			logger.handleError(a.id(), err)
+		}
 	}
+	if fb := logger.loadFallback(); fb != nil {
+		if err := fb.a.flush(); err != nil {
+			logger.handleError(fb.a.id(), err)
+		}
+	}
+}
+
+// Record is a log record passed to hooks.
+// Hooks may change any member of it.
+type Record struct {
+	Name     string
+	Time     time.Time
+	Level    Level
+	Function string
+	File     string
+	Line     int
+	Msg      interface{}
+	Fields   []Field
 }
 
 type message struct {
-	name     string
-	time     time.Time
-	level    Level
-	function string
-	file     string
-	line     int
-	msg      interface{}
+	Record
+	path      string
+	goroutine uint64
+	stack     []uintptr
+	encoded   []encoded // outputs cached by encoder
 }
 
 var messageCache = sync.Pool{
@@ -508,30 +710,43 @@ var messageCache = sync.Pool{
 	},
 }
 
-func (logger *Logger) write(v Level, o interface{}) {
-	skip := 2
+func (logger *Logger) write(v Level, o interface{}, fields []Field) {
+	skip := 2 + logger.callerSkip
 	if logger == lgr {
-		skip = 3
+		skip++
 	}
 
-	funcName := "null"
-	pc, file, line, ok := runtime.Caller(skip)
-	if !ok {
-		file = "null"
-		line = 0
-	} else {
-		funcName = runtime.FuncForPC(pc).Name()
+	s := logger.loadSettings()
+
+	var funcName, file, fileName string
+	var line int
+	if !s.noCaller {
+		funcName, file, line = callerOf(skip + 1)
+		_, fileName = path.Split(file)
 	}
-	_, fileName := path.Split(file)
 
 	msg := messageCache.Get().(*message)
-	msg.name = logger.name
-	msg.time = time.Now()
-	msg.level = v
-	msg.function = funcName
-	msg.file = fileName
-	msg.line = line
-	msg.msg = o
+	msg.Name = logger.name
+	msg.Time = time.Now()
+	msg.Level = v
+	msg.Function = funcName
+	msg.File = fileName
+	msg.path = file
+	msg.Line = line
+	msg.Msg = resolve(o)
+	if n := len(logger.fields); n > 0 {
+		fields = append(logger.fields[:n:n], fields...)
+	}
+	msg.Fields = resolveFields(fields)
+	msg.stack = nil
+	if (s.stackOn && v.atLeast(s.stackMin)) ||
+		(s.errStack && v.atLeast(LevelError) && msg.hasError()) {
+		msg.stack = callerStack(skip + 1)
+	}
+	msg.goroutine = 0
+	if s.captures&captureGoroutine != 0 {
+		msg.goroutine = goroutineID()
+	}
 
 	if logger.async {
 		logger.wait.Add(1)
@@ -549,189 +764,29 @@ func (logger *Logger) asyncProc() {
 }
 
 func (logger *Logger) writeToOutputs(msg *message) {
-	for _, a := range logger.adapters {
-		a.write(msg)
-	}
-	messageCache.Put(msg)
-}
-
-///////////////////////////////////////////////////////////////////////
-// log writer
-///////////////////////////////////////////////////////////////////////
-
-type logWriter func(w io.Writer, msg *message, prefix, suffix interface{})
-
-func makeWriter(format string, maxMsgLen uint32, prefixHolder, suffixHolder string) logWriter {
-	if len(format) == 0 {
-		return func(w io.Writer, msg *message, prefix, suffix interface{}) {
-		}
-	}
-
-	var (
-		namePos     = strings.Index(format, "$name")
-		ltimePos    = strings.Index(format, "$ltime")
-		stimePos    = strings.Index(format, "$stime")
-		tsPos       = strings.Index(format, "$ts")
-		ilevelPos   = strings.Index(format, "$ilevel")
-		slevelPos   = strings.Index(format, "$slevel")
-		functionPos = strings.Index(format, "$function")
-		filePos     = strings.Index(format, "$file")
-		linePos     = strings.Index(format, "$line")
-		msgPos      = strings.Index(format, "$msg")
-	)
-
-	var argMap = make(map[int]string)
-
-	if namePos != -1 {
-		argMap[namePos] = "$name"
-		format = strings.Replace(format, "$name", "%s", 1)
-	}
-	if ltimePos != -1 {
-		argMap[ltimePos] = "$ltime"
-		format = strings.Replace(format, "$ltime", "%s", 1)
-	}
-	if stimePos != -1 {
-		argMap[stimePos] = "$stime"
-		format = strings.Replace(format, "$stime", "%s", 1)
-	}
-	if tsPos != -1 {
-		argMap[tsPos] = "$ts"
-		format = strings.Replace(format, "$ts", "%d", 1)
-	}
-	if ilevelPos != -1 {
-		argMap[ilevelPos] = "$ilevel"
-		format = strings.Replace(format, "$ilevel", "%d", 1)
-	}
-	if slevelPos != -1 {
-		argMap[slevelPos] = "$slevel"
-		format = strings.Replace(format, "$slevel", "%s", 1)
-	}
-	if functionPos != -1 {
-		argMap[functionPos] = "$function"
-		format = strings.Replace(format, "$function", "%s", 1)
-	}
-	if filePos != -1 {
-		argMap[filePos] = "$file"
-		format = strings.Replace(format, "$file", "%s", 1)
-	}
-	if linePos != -1 {
-		argMap[linePos] = "$line"
-		format = strings.Replace(format, "$line", "%d", 1)
-	}
-	if msgPos != -1 {
-		argMap[msgPos] = "$msg"
-		if maxMsgLen == 0 {
-			format = strings.Replace(format, "$msg", "%+v", 1)
-		} else {
-			format = strings.Replace(format, "$msg", "%s", 1)
-		}
-	}
-
-	if len(prefixHolder) > 0 {
-		argMap[-1] = "$prefix"
-		format = prefixHolder + format
-	}
-	if len(suffixHolder) > 0 {
-		argMap[math.MaxInt32] = "$suffix"
-		format = format + suffixHolder
-	}
-
-	format += lineFeed
-
-	var argKeys []int
-	for k := range argMap {
-		argKeys = append(argKeys, k)
-	}
-	sort.Ints(argKeys)
-	argLen := len(argKeys)
-
-	prefixArgPicker := func(msg *message, prefix, suffix interface{}) interface{} {
-		return prefix
-	}
-	suffixArgPicker := func(msg *message, prefix, suffix interface{}) interface{} {
-		return suffix
-	}
-	nameArgPicker := func(msg *message, prefix, suffix interface{}) interface{} {
-		return msg.name
-	}
-	ltimeArgPicker := func(msg *message, prefix, suffix interface{}) interface{} {
-		return msg.time.Format("2006-01-02 15:04:05.000")
-	}
-	stimeArgPicker := func(msg *message, prefix, suffix interface{}) interface{} {
-		return msg.time.Format("15:04:05.000")
-	}
-	tsArgPicker := func(msg *message, prefix, suffix interface{}) interface{} {
-		return msg.time.UnixNano() / 1e3
-	}
-	ilevelArgPicker := func(msg *message, prefix, suffix interface{}) interface{} {
-		return int(msg.level)
-	}
-	slevelArgPicker := func(msg *message, prefix, suffix interface{}) interface{} {
-		return levelString[msg.level]
-	}
-	functionArgPicker := func(msg *message, prefix, suffix interface{}) interface{} {
-		return msg.function
-	}
-	fileArgPicker := func(msg *message, prefix, suffix interface{}) interface{} {
-		return msg.file
-	}
-	lineArgPicker := func(msg *message, prefix, suffix interface{}) interface{} {
-		return msg.line
-	}
-	msgArgPicker := func(msg *message, prefix, suffix interface{}) interface{} {
-		if maxMsgLen == 0 {
-			return msg.msg
-		}
-
-		str := fmt.Sprintf("%+v", msg.msg)
-		if uint32(len(str)) > maxMsgLen {
-			str = str[:maxMsgLen] + " ..."
+	if !runHooks(logger.loadHooks(), &msg.Record) {
+		atomic.AddUint64(&logger.counters.dropped, 1)
+	} else {
+		logger.counters.count(msg.Name, msg.Level)
+		if r := logger.loadRedactor(); r != nil {
+			r.redact(&msg.Record)
 		}
-		return str
-	}
-
-	pickerList := make([]func(msg *message, prefix, suffix interface{}) interface{}, argLen)
-	for i, k := range argKeys {
-		kStr := argMap[k]
-		switch kStr {
-		case "$prefix":
-			pickerList[i] = prefixArgPicker
-		case "$suffix":
-			pickerList[i] = suffixArgPicker
-		case "$name":
-			pickerList[i] = nameArgPicker
-		case "$ltime":
-			pickerList[i] = ltimeArgPicker
-		case "$stime":
-			pickerList[i] = stimeArgPicker
-		case "$ts":
-			pickerList[i] = tsArgPicker
-		case "$ilevel":
-			pickerList[i] = ilevelArgPicker
-		case "$slevel":
-			pickerList[i] = slevelArgPicker
-		case "$function":
-			pickerList[i] = functionArgPicker
-		case "$file":
-			pickerList[i] = fileArgPicker
-		case "$line":
-			pickerList[i] = lineArgPicker
-		case "$msg":
-			pickerList[i] = msgArgPicker
+		failed, written := false, false
+		for _, a := range logger.loadAdapters() {
+			wrote, err := a.write(msg)
+			if err != nil {
+				logger.handleError(a.id(), err)
+				failed = true
+			}
+			written = written || wrote
 		}
-	}
 
-	argComposer := func(msg *message, prefix, suffix interface{}) []interface{} {
-		args := make([]interface{}, argLen)
-		for i, p := range pickerList {
-			a := p(msg, prefix, suffix)
-			args[i] = a
+		if fb := logger.loadFallback(); failed && !written && fb != nil {
+			if _, err := fb.a.write(msg); err != nil {
+				logger.handleError(fb.a.id(), err)
+			}
 		}
-		return args
-	}
-
-	return func(w io.Writer, msg *message, prefix, suffix interface{}) {
-		args := argComposer(msg, prefix, suffix)
-		fmt.Fprintf(w, format, args...)
 	}
+	msg.release()
+	messageCache.Put(msg)
 }
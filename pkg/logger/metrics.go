@@ -0,0 +1,202 @@
+package logger
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Metrics is a snapshot of counters of a logger and its children.
+type Metrics struct {
+	Records    []RecordCount // records passed hooks, ordered by name and level
+	Suppressed uint64        // records below level
+	Dropped    uint64        // records dropped by hooks
+	Failed     uint64        // adapter write and flush errors
+}
+
+// RecordCount is the number of records of a logger name and a level.
+type RecordCount struct {
+	Name  string
+	Level Level
+	Count uint64
+}
+
+// ByLevel sums counts of records by level.
+func (m Metrics) ByLevel() map[Level]uint64 {
+	levels := map[Level]uint64{}
+	for _, r := range m.Records {
+		levels[r.Level] += r.Count
+	}
+	return levels
+}
+
+// ByName sums counts of records by logger name.
+func (m Metrics) ByName() map[string]uint64 {
+	names := map[string]uint64{}
+	for _, r := range m.Records {
+		names[r.Name] += r.Count
+	}
+	return names
+}
+
+// GetMetrics for singleton
+func GetMetrics() Metrics { return lgr.Metrics() }
+
+// Metrics returns counters shared by the logger and its children.
+func (logger *Logger) Metrics() Metrics {
+	c := logger.counters
+	m := Metrics{
+		Suppressed: atomic.LoadUint64(&c.suppressed),
+		Dropped:    atomic.LoadUint64(&c.dropped),
+		Failed:     atomic.LoadUint64(&c.failed),
+	}
+	for k, n := range c.loadRecords() {
+		m.Records = append(m.Records, RecordCount{Name: k.name, Level: k.level, Count: atomic.LoadUint64(n)})
+	}
+	sort.Slice(m.Records, func(i, j int) bool {
+		a, b := m.Records[i], m.Records[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Level.severity() < b.Level.severity()
+	})
+	return m
+}
+
+// PublishExpvar for singleton
+func PublishExpvar(name string) { lgr.PublishExpvar(name) }
+
+// PublishExpvar publishes metrics as an expvar variable:
+//
+//	{"records":{"Default":{"error":1,...},...},"suppressed":0,"dropped":0,"failed":0}
+//
+// If name is already published, the existing variable is kept
+// and PublishExpvar does nothing, unlike expvar.Publish which panics.
+func (logger *Logger) PublishExpvar(name string) {
+	if expvar.Get(name) != nil {
+		return
+	}
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		m := logger.Metrics()
+		records := map[string]map[string]uint64{}
+		for _, r := range m.Records {
+			if records[r.Name] == nil {
+				records[r.Name] = map[string]uint64{}
+			}
+			records[r.Name][levelName(r.Level)] = r.Count
+		}
+		return map[string]interface{}{
+			"records":    records,
+			"suppressed": m.Suppressed,
+			"dropped":    m.Dropped,
+			"failed":     m.Failed,
+		}
+	}))
+}
+
+// MetricsHandler for singleton
+func MetricsHandler() http.Handler { return lgr.MetricsHandler() }
+
+// MetricsHandler returns a handler which serves metrics
+// in Prometheus text exposition format.
+func (logger *Logger) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w, logger.Metrics())
+	})
+}
+
+func writeMetrics(w io.Writer, m Metrics) {
+	fmt.Fprintln(w, "# HELP logger_records_total Records written by logger name and level.")
+	fmt.Fprintln(w, "# TYPE logger_records_total counter")
+	for _, r := range m.Records {
+		fmt.Fprintf(w, "logger_records_total{name=\"%s\",level=\"%s\"} %d\n",
+			escapeLabel(r.Name), escapeLabel(levelName(r.Level)), r.Count)
+	}
+
+	for _, c := range []struct {
+		name, help string
+		value      uint64
+	}{
+		{"logger_suppressed_total", "Records below level.", m.Suppressed},
+		{"logger_dropped_total", "Records dropped by hooks.", m.Dropped},
+		{"logger_failures_total", "Adapter write and flush errors.", m.Failed},
+	} {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.name, c.help, c.name, c.name, c.value)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// levelName returns long name of a level, or "L<n>" for unknown level.
+func levelName(l Level) string {
+	if spec, ok := LookupLevel(l); ok {
+		return spec.Name
+	}
+	return l.String()
+}
+
+///////////////////////////////////////////////////////////////////////
+
+type recordKey struct {
+	name  string
+	level Level
+}
+
+// counters are shared by a logger and its children.
+type counters struct {
+	suppressed uint64 // atomic, first for 64-bit alignment
+	dropped    uint64 // atomic
+	failed     uint64 // atomic
+
+	lock    sync.Mutex   // serializes adding keys
+	records atomic.Value // map[recordKey]*uint64, copied on write
+}
+
+func (c *counters) loadRecords() map[recordKey]*uint64 {
+	records, _ := c.records.Load().(map[recordKey]*uint64)
+	return records
+}
+
+// count counts a record, it adds a key on the first record of the key.
+func (c *counters) count(name string, level Level) {
+	key := recordKey{name: name, level: level}
+	if n, ok := c.loadRecords()[key]; ok {
+		atomic.AddUint64(n, 1)
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	old := c.loadRecords()
+	if n, ok := old[key]; ok {
+		atomic.AddUint64(n, 1)
+		return
+	}
+	records := make(map[recordKey]*uint64, len(old)+1)
+	for k, v := range old {
+		records[k] = v
+	}
+	n := uint64(1)
+	records[key] = &n
+	c.records.Store(records)
+}
+
+// enabled is Enabled counting suppressed records.
+func (logger *Logger) enabled(l Level) bool {
+	if logger.Enabled(l) {
+		return true
+	}
+	atomic.AddUint64(&logger.counters.suppressed, 1)
+	return false
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"errors"
+	"expvar"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) { return 0, errors.New("broken") }
+
+func TestMetrics(t *testing.T) {
+	l := New("app", false)
+	c := NewConsoleAdapterConfig()
+	c.Writer = io.Discard
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+	l.SetLevel(LevelInformation)
+	l.AddHook(func(r *Record) bool { return r.Msg != "drop" })
+
+	db := l.Named("db")
+	l.Information("a")
+	l.Error("b")
+	db.Error("c")
+	db.Error("drop")
+	db.Debug("d")
+
+	m := l.Metrics()
+	if m.Suppressed != 1 || m.Dropped != 1 || m.Failed != 0 {
+		t.Errorf("unexpected counts: %+v", m)
+	}
+	if got := m.ByLevel()[LevelError]; got != 2 {
+		t.Errorf("errors: got %d, want 2", got)
+	}
+	if got := m.ByName()["app.db"]; got != 1 {
+		t.Errorf("app.db: got %d, want 1", got)
+	}
+
+	l.Detach(AdapterConsole)
+	c.Writer = failWriter{}
+	l.Attach(c)
+	l.Warning("e")
+	if m := db.Metrics(); m.Failed != 1 {
+		t.Errorf("failed: got %d, want 1", m.Failed)
+	}
+
+	rec := httptest.NewRecorder()
+	l.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`logger_records_total{name="app",level="error"} 1`,
+		`logger_records_total{name="app.db",level="error"} 1`,
+		`logger_records_total{name="app",level="warning"} 1`,
+		"logger_failures_total 1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+
+	l.PublishExpvar("logger_test")
+	// a name already published is kept without panic
+	l.PublishExpvar("logger_test")
+	if v := expvar.Get("logger_test").String(); !strings.Contains(v, `"app.db":{"error":1}`) {
+		t.Errorf("expvar: got %s", v)
+	}
+}
@@ -0,0 +1,129 @@
+package logger
+
+import "strings"
+
+// Named for singleton
+func Named(name string) *Logger { return lgr.Named(name) }
+
+// Named returns a child logger whose name is the logger's name
+// followed by a dot and name, e.g. "Default.db".
+// The child shares adapters, hooks and other settings with the logger,
+// so attaching an adapter to either affects both.
+// It inherits the logger's fields and, unless set for its name, the logger's level.
+func (logger *Logger) Named(name string) *Logger {
+	if len(name) == 0 {
+		return logger
+	}
+	return &Logger{
+		core:       logger.core,
+		name:       logger.name + "." + name,
+		fields:     logger.fields,
+		callerSkip: logger.callerSkip,
+	}
+}
+
+// With for singleton
+func With(fields ...Field) *Logger { return lgr.With(fields...) }
+
+// With returns a child logger of the same name
+// which attaches fields to every record.
+func (logger *Logger) With(fields ...Field) *Logger {
+	n := len(logger.fields)
+	return &Logger{
+		core:       logger.core,
+		name:       logger.name,
+		fields:     append(logger.fields[:n:n], fields...),
+		callerSkip: logger.callerSkip,
+	}
+}
+
+// Name returns the name of the logger.
+func (logger *Logger) Name() string {
+	return logger.name
+}
+
+// SetNameLevel for singleton
+func SetNameLevel(name string, l Level) error { return lgr.SetNameLevel(name, l) }
+
+// SetNameLevel sets level of loggers named name and their descendants,
+// e.g. "Default.db" applies to "Default.db" and "Default.db.pool".
+// The level of the longest matching name is used.
+// Levels can be set before the loggers are made.
+func (logger *Logger) SetNameLevel(name string, l Level) error {
+	if !l.valid() {
+		return ErrInvalidLevel
+	}
+
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	levels := logger.loadLevels().clone()
+	levels[name] = l
+	logger.levels.Store(levels)
+	logger.updateAdapters()
+	return nil
+}
+
+// ClearNameLevel for singleton
+func ClearNameLevel(name string) { lgr.ClearNameLevel(name) }
+
+// ClearNameLevel removes level set for name,
+// loggers of the name follow the level of their parent again.
+// Loggers without any level set default to LevelDebug.
+func (logger *Logger) ClearNameLevel(name string) {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	levels := logger.loadLevels().clone()
+	delete(levels, name)
+	logger.levels.Store(levels)
+	logger.updateAdapters()
+}
+
+///////////////////////////////////////////////////////////////////////
+
+// nameLevels is levels set by logger name, copied on write.
+type nameLevels map[string]Level
+
+func (c *core) loadLevels() nameLevels {
+	levels, _ := c.levels.Load().(nameLevels)
+	return levels
+}
+
+func (n nameLevels) clone() nameLevels {
+	levels := make(nameLevels, len(n)+1)
+	for k, v := range n {
+		levels[k] = v
+	}
+	return levels
+}
+
+// resolve returns level of the longest name which is name itself
+// or one of its dotted prefixes.
+func (n nameLevels) resolve(name string) Level {
+	for {
+		if l, ok := n[name]; ok {
+			return l
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return LevelDebug
+		}
+		name = name[:i]
+	}
+}
+
+// min returns the least severe level which a logger of root can resolve.
+func (n nameLevels) min(root string) Level {
+	// loggers without level set default to LevelDebug
+	min, ok := n[root]
+	if !ok {
+		min = LevelDebug
+	}
+	for _, l := range n {
+		if l.severity() < min.severity() {
+			min = l
+		}
+	}
+	return min
+}
@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNamed(t *testing.T) {
+	var out bytes.Buffer
+
+	l := New("app", false)
+	c := NewConsoleAdapterConfig()
+	c.Color = false
+	c.Format = "$name $msg$fields"
+	c.Writer = &out
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+
+	db := l.With(F("pid", 1)).Named("db")
+	pool := db.Named("pool").With(F("conn", 2))
+
+	l.Information("start")
+	db.Information("open")
+	pool.Information("acquire", F("wait", 3))
+
+	want := "app start" + lineFeed +
+		"app.db open pid=1" + lineFeed +
+		"app.db.pool acquire pid=1 conn=2 wait=3" + lineFeed
+	if got := out.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNameLevel(t *testing.T) {
+	var out bytes.Buffer
+
+	l := New("app", false)
+	c := NewConsoleAdapterConfig()
+	c.Color = false
+	c.Format = "$name $msg"
+	c.Writer = &out
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+
+	db := l.Named("db")
+	pool := db.Named("pool")
+	http := l.Named("http")
+
+	// set before the logger is made
+	if err := l.SetNameLevel("app.db.pool", LevelDebug); err != nil {
+		t.Fatal(err)
+	}
+	l.SetLevel(LevelWarning)
+	db.SetLevel(LevelError)
+
+	tests := []struct {
+		l    *Logger
+		lv   Level
+		want bool
+	}{
+		{l, LevelInformation, false},
+		{l, LevelWarning, true},
+		{http, LevelInformation, false},
+		{http, LevelWarning, true},
+		{db, LevelWarning, false},
+		{db, LevelError, true},
+		{pool, LevelDebug, true},
+		{l.Named("dbx"), LevelWarning, true},
+	}
+	for _, tt := range tests {
+		if got := tt.l.Enabled(tt.lv); got != tt.want {
+			t.Errorf("%s %v: got %v, want %v", tt.l.Name(), tt.lv, got, tt.want)
+		}
+	}
+
+	pool.Debug("debug")
+	db.Warning("warn")
+	if got, want := out.String(), "app.db.pool debug"+lineFeed; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	l.ClearNameLevel("app.db")
+	if !db.Enabled(LevelWarning) || db.Enabled(LevelInformation) {
+		t.Error("cleared level should follow parent")
+	}
+}
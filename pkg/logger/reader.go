@@ -0,0 +1,540 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRecord is returned by Reader for text which doesn't match the format.
+var ErrInvalidRecord = errors.New("invalid log record")
+
+// ReaderConfig is the output options of the adapter which wrote the log.
+type ReaderConfig struct {
+	Format   string
+	Encoding Encoding
+	TimeZone string // "" for local, "UTC" or IANA time zone name
+	Key      []byte // key of encrypted logs, see FileAdapterConfig.EncryptionKey
+	Follow   bool   // the input grows, see Reader.Flush
+}
+
+// NewReaderConfig returns a new ReaderConfig instance.
+func NewReaderConfig() *ReaderConfig {
+	return &ReaderConfig{
+		Format:   DefaultFormat,
+		Encoding: EncodingText,
+	}
+}
+
+// Reader parses records written by adapters back.
+//
+// A text record spans lines until a line which starts like a record,
+// so that multi-line messages and stack traces are kept in the record.
+// It needs tokens of fixed shape such as $ltime or $slevel before
+// $msg in the format, otherwise every line is a record.
+// Lines following the text of a record are set to "stack" field.
+//
+// Token values without a Record member, e.g. $pid, are dropped,
+// and field values of text format are strings.
+// $msg directly followed by $fields, as in DefaultFormat, can't be split
+// unambiguously: trailing " key=value" words of a message are read as fields.
+// A format with a separator between them, e.g. "$msg |$fields", keeps them.
+type Reader struct {
+	r      *bufio.Reader
+	closer []io.Closer
+	parse  func(text string, rec *Record) bool
+	start  *regexp.Regexp // matches the beginning of a record, nil for every line
+	json   bool
+	follow bool
+
+	text    string // text of the last record
+	pending string
+	lineNo  int // line of pending
+	next    int // line to be read
+}
+
+// OpenReader opens a log file, gzip compressed files included.
+func OpenReader(name string, c *ReaderConfig) (*Reader, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := NewReader(f, c)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = append(r.closer, f)
+	return r, nil
+}
+
+// NewReader makes a Reader from r.
+// gzip compressed and encrypted input is detected and decoded.
+// A truncated frame at the end of encrypted input is read as the end.
+func NewReader(r io.Reader, c *ReaderConfig) (*Reader, error) {
+	if c == nil {
+		return nil, ErrNilConfig
+	}
+
+	loc, err := loadLocation(c.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	rd := &Reader{follow: c.Follow}
+	switch c.Encoding {
+	case EncodingText:
+		if err := rd.compile(c.Format, loc); err != nil {
+			return nil, err
+		}
+	case EncodingJSON:
+		rd.json = true
+	default:
+		return nil, ErrInvalidConfig
+	}
+
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		rd.closer = append(rd.closer, zr)
+		br = bufio.NewReader(zr)
+	}
+	if magic, _ := br.Peek(len(encryptMagic)); string(magic) == encryptMagic {
+		if c.Key == nil {
+			return nil, fmt.Errorf("encrypted log without key: %w", ErrInvalidConfig)
+		}
+		dr, err := NewDecryptReader(br, c.Key)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(dr)
+	}
+	rd.r = br
+	return rd, nil
+}
+
+// Close closes the file opened by OpenReader.
+func (rd *Reader) Close() error {
+	var err error
+	for i := len(rd.closer) - 1; i >= 0; i-- {
+		if e := rd.closer[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	rd.closer = nil
+	return err
+}
+
+// Read returns the next record, or io.EOF at the end of input.
+// A record which can't be parsed is reported as ErrInvalidRecord
+// with its line number, and reading can continue.
+// Read after io.EOF returns records appended to the input since then,
+// which is used to follow a growing file.
+// With Follow, the last text record is kept at io.EOF, since lines of it
+// may be appended yet. It's returned when the next record starts or by Flush.
+func (rd *Reader) Read() (*Record, error) {
+	for {
+		line, err := rd.readLine()
+		if err == io.EOF {
+			if rd.lineNo == 0 || rd.follow && !rd.json && rd.start != nil {
+				return nil, io.EOF
+			}
+			return rd.emit("", 0)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if rd.json && len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		if rd.lineNo == 0 {
+			rd.pending, rd.lineNo = line, rd.next
+			continue
+		}
+		if rd.json || rd.start == nil || rd.start.MatchString(line) {
+			return rd.emit(line, rd.next)
+		}
+		rd.pending += lineFeed + line
+	}
+}
+
+// Flush returns the record kept by Read with Follow,
+// e.g. when the input doesn't grow for a while or before Close.
+// It returns io.EOF when no record is kept.
+func (rd *Reader) Flush() (*Record, error) {
+	if rd.lineNo == 0 {
+		return nil, io.EOF
+	}
+	return rd.emit("", 0)
+}
+
+// emit parses pending text and keeps line as the next pending,
+// lineNo 0 means no more line.
+func (rd *Reader) emit(line string, lineNo int) (*Record, error) {
+	text, textNo := rd.pending, rd.lineNo
+	rd.pending, rd.lineNo = line, lineNo
+	rd.text = text
+
+	rec := &Record{}
+	var ok bool
+	if rd.json {
+		ok = parseJSONRecord(text, rec)
+	} else {
+		ok = rd.parseText(text, rec)
+	}
+	if !ok {
+		return nil, fmt.Errorf("line %d: %w", textNo, ErrInvalidRecord)
+	}
+	return rec, nil
+}
+
+// Text returns the text of the record last read, valid or not.
+// Lines of the text are joined by lineFeed.
+func (rd *Reader) Text() string {
+	return rd.text
+}
+
+func (rd *Reader) readLine() (string, error) {
+	line, err := rd.r.ReadString('\n')
+	if err == ErrTruncatedFrame {
+		// lost by a crash while writing
+		err = io.EOF
+	}
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	rd.next++
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), nil
+}
+
+// parseText parses text of a record.
+// Trailing lines which don't match are set to "stack" field.
+func (rd *Reader) parseText(text string, rec *Record) bool {
+	end := len(text)
+	for {
+		if rd.parse(text[:end], rec) {
+			if end < len(text) {
+				stack := strings.TrimPrefix(text[end:], lineFeed)
+				rec.Fields = append(rec.Fields, F("stack", stack))
+			}
+			return true
+		}
+
+		end = strings.LastIndex(text[:end], lineFeed)
+		if end < 0 {
+			return false
+		}
+		*rec = Record{}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////
+// text format
+///////////////////////////////////////////////////////////////////////
+
+// tokenParser sets a matched token value to a record.
+type tokenParser func(v string, rec *Record) bool
+
+// compile makes a regular expression of format,
+// each token is a group parsed by its tokenParser.
+func (rd *Reader) compile(format string, loc *time.Location) error {
+	if len(format) == 0 {
+		return ErrInvalidFormat
+	}
+
+	tokens, err := parseFormat(format)
+	if err != nil {
+		return err
+	}
+
+	var (
+		expr    strings.Builder
+		start   string
+		fixed   = true
+		parsers []tokenParser
+	)
+	expr.WriteString("^")
+	for _, t := range tokens {
+		if len(t.name) == 0 {
+			expr.WriteString(regexp.QuoteMeta(t.text))
+			if fixed {
+				start = expr.String()
+			}
+			continue
+		}
+
+		pattern, strict, parser, err := tokenPattern(t, loc)
+		if err != nil {
+			return formatError(format, t.pos, err.Error())
+		}
+		if len(t.param) > 0 && t.name != "time" && t.name != "unix" {
+			// padded by width
+			pattern = " *" + pattern + " *"
+		}
+		expr.WriteString(pattern)
+		parsers = append(parsers, parser)
+
+		fixed = fixed && strict
+		if fixed {
+			start = expr.String()
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return formatError(format, 0, err.Error())
+	}
+	if len(start) > 1 {
+		rd.start = regexp.MustCompile(start)
+	}
+
+	rd.parse = func(text string, rec *Record) bool {
+		m := re.FindStringSubmatch(text)
+		if m == nil {
+			return false
+		}
+		for i, p := range parsers {
+			if !p(m[i+1], rec) {
+				return false
+			}
+		}
+		return true
+	}
+	return nil
+}
+
+// tokenPattern returns a regular expression group of a token.
+// strict is true when the pattern can't match arbitrary text.
+func tokenPattern(t formatToken, loc *time.Location) (pattern string, strict bool, parser tokenParser, err error) {
+	timeParser := func(layout string) tokenParser {
+		return func(v string, rec *Record) bool {
+			tm, err := time.ParseInLocation(layout, v, loc)
+			rec.Time = tm
+			return err == nil
+		}
+	}
+	ignore := func(v string, rec *Record) bool { return true }
+
+	switch t.name {
+	case "name":
+		return `(.*?)`, false, func(v string, rec *Record) bool {
+			rec.Name = v
+			return true
+		}, nil
+	case "ltime":
+		return `(\d{4}-\d\d-\d\d \d\d:\d\d:\d\d\.\d{3})`, true, timeParser("2006-01-02 15:04:05.000"), nil
+	case "stime":
+		return `(\d\d:\d\d:\d\d\.\d{3})`, true, timeParser("15:04:05.000"), nil
+	case "time":
+		layout := t.param
+		if l, ok := timeLayouts[t.param]; ok {
+			layout = l
+		} else if len(layout) == 0 {
+			layout = time.RFC3339
+		}
+		return `(.+?)`, false, timeParser(layout), nil
+	case "ts", "unix":
+		var mul int64 = 1e3
+		if t.name == "unix" {
+			switch t.param {
+			case "", "s":
+				mul = 1e9
+			case "ms":
+				mul = 1e6
+			case "us":
+				mul = 1e3
+			case "ns":
+				mul = 1
+			default:
+				return "", false, nil, fmt.Errorf("unknown unix time unit %q", t.param)
+			}
+		}
+		return `(-?\d+)`, true, func(v string, rec *Record) bool {
+			n, err := strconv.ParseInt(v, 10, 64)
+			rec.Time = time.Unix(0, n*mul).In(loc)
+			return err == nil
+		}, nil
+	case "ilevel":
+		return `(-?\d+)`, true, func(v string, rec *Record) bool {
+			n, err := strconv.Atoi(v)
+			rec.Level = Level(n)
+			return err == nil
+		}, nil
+	case "slevel":
+		var codes []string
+		for _, l := range Levels() {
+			codes = append(codes, regexp.QuoteMeta(l.String()))
+		}
+		return `(` + strings.Join(codes, "|") + `|L-?\d+)`, true, func(v string, rec *Record) bool {
+			l, ok := parseLevel(v)
+			rec.Level = l
+			return ok
+		}, nil
+	case "function":
+		return `(.+?)`, false, func(v string, rec *Record) bool {
+			rec.Function = v
+			return true
+		}, nil
+	case "file", "pkgfile", "path":
+		return `(.+?)`, false, func(v string, rec *Record) bool {
+			rec.File = path.Base(v)
+			return true
+		}, nil
+	case "line":
+		return `(\d+)`, true, func(v string, rec *Record) bool {
+			n, err := strconv.Atoi(v)
+			rec.Line = n
+			return err == nil
+		}, nil
+	case "msg":
+		return `((?s:.*?))`, false, func(v string, rec *Record) bool {
+			rec.Msg = v
+			return true
+		}, nil
+	case "fields":
+		return `((?: [^\s=]+=(?:"(?:[^"\\]|\\.)*"|\S*))*)`, false, func(v string, rec *Record) bool {
+			fields, ok := parseFields(v)
+			rec.Fields = fields
+			return ok
+		}, nil
+	case "pid", "goroutine":
+		return `(\d+)`, true, ignore, nil
+	case "hostname":
+		return `(\S+)`, false, ignore, nil
+	}
+	return "", false, nil, fmt.Errorf("unknown token %q", t.name)
+}
+
+// parseLevel parses short code of a level, or "L<n>" of unknown level.
+func parseLevel(s string) (Level, bool) {
+	for l, spec := range levelSpecs() {
+		if spec.Short == s {
+			return l, true
+		}
+	}
+	if strings.HasPrefix(s, "L") {
+		if n, err := strconv.Atoi(s[1:]); err == nil {
+			return Level(n), true
+		}
+	}
+	return LevelDebug, false
+}
+
+// parseFields parses " key=value" list of $fields.
+func parseFields(s string) ([]Field, bool) {
+	var fields []Field
+	for len(s) > 0 {
+		s = strings.TrimPrefix(s, " ")
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, false
+		}
+		key := s[:eq]
+		s = s[eq+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			q, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, false
+			}
+			value, _ = strconv.Unquote(q)
+			s = s[len(q):]
+		} else {
+			end := strings.IndexByte(s, ' ')
+			if end < 0 {
+				end = len(s)
+			}
+			value = s[:end]
+			s = s[end:]
+		}
+		fields = append(fields, F(key, value))
+	}
+	return fields, true
+}
+
+///////////////////////////////////////////////////////////////////////
+// JSON encoding
+///////////////////////////////////////////////////////////////////////
+
+// parseJSONRecord parses a line of JSON encoding.
+// Members other than the record's are set to fields in order.
+func parseJSONRecord(text string, rec *Record) bool {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return false
+	}
+
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		key, _ := t.(string)
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return false
+		}
+
+		var ok bool
+		switch key {
+		case "time":
+			var s string
+			if ok = json.Unmarshal(raw, &s) == nil; ok {
+				rec.Time, err = time.Parse(time.RFC3339Nano, s)
+				ok = err == nil
+			}
+		case "level":
+			var s string
+			if ok = json.Unmarshal(raw, &s) == nil; ok {
+				rec.Level, ok = parseLevel(s)
+			}
+		case "name":
+			ok = json.Unmarshal(raw, &rec.Name) == nil
+		case "msg":
+			var s string
+			ok = json.Unmarshal(raw, &s) == nil
+			rec.Msg = s
+		case "function":
+			ok = json.Unmarshal(raw, &rec.Function) == nil
+		case "file":
+			ok = json.Unmarshal(raw, &rec.File) == nil
+		case "line":
+			ok = json.Unmarshal(raw, &rec.Line) == nil
+		default:
+			var v interface{}
+			d := json.NewDecoder(bytes.NewReader(raw))
+			d.UseNumber()
+			ok = d.Decode(&v) == nil
+			rec.Fields = append(rec.Fields, F(strings.TrimPrefix(key, jsonFieldPrefix), v))
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	_, err := dec.Token()
+	return err == nil
+}
@@ -0,0 +1,168 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readAll(t *testing.T, rd *Reader) []*Record {
+	t.Helper()
+	var recs []*Record
+	for {
+		rec, err := rd.Read()
+		if err == io.EOF {
+			return recs
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, rec)
+	}
+}
+
+func TestReader(t *testing.T) {
+	for _, encoding := range []Encoding{EncodingText, EncodingJSON} {
+		filename := filepath.Join(t.TempDir(), "app.log")
+
+		l := New("app", false)
+		c := NewFileAdapterConfig()
+		c.Filename = filename
+		c.Encoding = encoding
+		c.TimeZone = "UTC"
+		if err := l.Attach(c); err != nil {
+			t.Fatal(err)
+		}
+		l.SetStackLevel(LevelError)
+
+		l.Named("db").Information("first line\nsecond line", F("user", "a b"))
+		l.Error("failed")
+		l.Detach(AdapterFile)
+
+		rc := NewReaderConfig()
+		rc.Encoding = encoding
+		rc.TimeZone = "UTC"
+		rd, err := OpenReader(filename, rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recs := readAll(t, rd)
+		rd.Close()
+
+		if len(recs) != 2 {
+			t.Fatalf("encoding %d: got %d records", encoding, len(recs))
+		}
+		r := recs[0]
+		if encoding == EncodingJSON && r.Name != "app.db" {
+			t.Errorf("name: got %q", r.Name)
+		}
+		if r.Level != LevelInformation || r.Msg != "first line\nsecond line" || r.File != "reader_test.go" || r.Line == 0 {
+			t.Errorf("encoding %d: unexpected record %+v", encoding, r)
+		}
+		if len(r.Fields) != 1 || r.Fields[0].Key != "user" || r.Fields[0].Value != "a b" {
+			t.Errorf("encoding %d: unexpected fields %+v", encoding, r.Fields)
+		}
+		if r.Time.IsZero() {
+			t.Errorf("encoding %d: no time", encoding)
+		}
+
+		r = recs[1]
+		if r.Level != LevelError || r.Msg != "failed" || len(r.Fields) != 1 || r.Fields[0].Key != "stack" {
+			t.Errorf("encoding %d: unexpected record %+v", encoding, r)
+		}
+	}
+}
+
+func TestReaderInvalidFormat(t *testing.T) {
+	rc := NewReaderConfig()
+	rc.Format = "$ltime [$levle] $msg"
+	if _, err := NewReader(strings.NewReader(""), rc); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("got %v, want ErrInvalidFormat", err)
+	}
+}
+
+func TestReaderFollow(t *testing.T) {
+	var input bytes.Buffer
+	rc := NewReaderConfig()
+	rc.Format = "$ltime [$slevel] $msg"
+	rc.TimeZone = "UTC"
+	rc.Follow = true
+	rd, err := NewReader(&input, rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first record may continue
+	input.WriteString("2021-03-04 05:06:07.008 [INF] first\n")
+	if _, err := rd.Read(); err != io.EOF {
+		t.Fatalf("got %v, want io.EOF", err)
+	}
+	input.WriteString("  continued\n")
+	if _, err := rd.Read(); err != io.EOF {
+		t.Fatalf("got %v, want io.EOF", err)
+	}
+
+	input.WriteString("2021-03-04 05:06:08.000 [ERR] second\n")
+	rec, err := rd.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Msg != "first\n  continued" {
+		t.Errorf("first: got %q", rec.Msg)
+	}
+	if _, err := rd.Read(); err != io.EOF {
+		t.Fatalf("got %v, want io.EOF", err)
+	}
+
+	rec, err = rd.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Msg != "second" || rec.Level != LevelError {
+		t.Errorf("second: got %+v", rec)
+	}
+	if _, err := rd.Flush(); err != io.EOF {
+		t.Errorf("got %v, want io.EOF", err)
+	}
+}
+
+func TestReaderGzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	io.WriteString(zw, "garbage"+lineFeed+
+		"2021-03-04 05:06:07.008 [WRN] hello n=1 (main.go:42)"+lineFeed+
+		"2021-03-04 05:06:08.000 [L99] custom (main.go:43)"+lineFeed)
+	zw.Close()
+
+	filename := filepath.Join(t.TempDir(), "app.log.20210304")
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rc := NewReaderConfig()
+	rc.TimeZone = "UTC"
+	rd, err := OpenReader(filename, rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rd.Close()
+
+	if _, err := rd.Read(); !errors.Is(err, ErrInvalidRecord) || !strings.HasPrefix(err.Error(), "line 1:") {
+		t.Errorf("garbage: got %v", err)
+	}
+	recs := readAll(t, rd)
+	if len(recs) != 2 {
+		t.Fatalf("got %d records", len(recs))
+	}
+	if r := recs[0]; r.Msg != "hello" || r.Level != LevelWarning || r.Line != 42 || r.Time.Nanosecond() != 8000000 {
+		t.Errorf("unexpected record %+v", r)
+	}
+	if r := recs[1]; r.Level != Level(99) || r.Msg != "custom" {
+		t.Errorf("unexpected record %+v", r)
+	}
+}
@@ -0,0 +1,278 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// built-in redaction patterns
+var (
+	// RedactBearer matches bearer tokens, e.g. "Bearer eyJhbGciOi..."
+	RedactBearer = regexp.MustCompile(`(?i)\bbearer\s+[a-z0-9\-._~+/]+=*`)
+	// RedactEmail matches e-mail addresses.
+	RedactEmail = regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9\-]+(?:\.[a-zA-Z0-9\-]+)*\.[a-zA-Z]{2,}`)
+	// RedactPAN matches card numbers of 13 to 19 digits.
+	// Matches are masked only when they pass Luhn check.
+	RedactPAN = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+)
+
+// RedactConfig is configuration of redaction.
+type RedactConfig struct {
+	// Keys are names of sensitive values.
+	// A field or a "key=value"/"key:value" pair in a message is masked
+	// when its name ends with one of Keys, case insensitive.
+	// An unquoted value may have spaces, it ends at the next
+	// "key=" or "key:" pair, a closing '}' or ']', or the end.
+	Keys []string
+	// Patterns are masked in messages and field values.
+	Patterns []*regexp.Regexp
+	// Mask replaces sensitive values, "***" if empty.
+	Mask string
+}
+
+// NewRedactConfig returns a new RedactConfig instance
+// with common sensitive keys and all built-in patterns.
+func NewRedactConfig() *RedactConfig {
+	return &RedactConfig{
+		Keys: []string{
+			"password", "passwd", "secret", "token",
+			"authorization", "apikey", "api_key", "cookie",
+		},
+		Patterns: []*regexp.Regexp{RedactBearer, RedactEmail, RedactPAN},
+		Mask:     "***",
+	}
+}
+
+// SetRedaction for singleton
+func SetRedaction(c *RedactConfig) error { return lgr.SetRedaction(c) }
+
+// SetRedaction masks sensitive data of every record after hooks run,
+// before it is written to any adapter.
+// Non-string messages such as structs are rendered with %+v before masking.
+// nil config disables redaction.
+func (logger *Logger) SetRedaction(c *RedactConfig) error {
+	var r *redactor
+	if c != nil {
+		var err error
+		if r, err = newRedactor(c); err != nil {
+			return err
+		}
+	}
+
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	// read without lock by asyncProc
+	logger.redactor.Store(r)
+	return nil
+}
+
+func (logger *Logger) loadRedactor() *redactor {
+	r, _ := logger.redactor.Load().(*redactor)
+	return r
+}
+
+///////////////////////////////////////////////////////////////////////
+
+type redactor struct {
+	keys     []string
+	keyRe    *regexp.Regexp
+	patterns []*regexp.Regexp
+	mask     string
+}
+
+func newRedactor(c *RedactConfig) (*redactor, error) {
+	r := &redactor{
+		patterns: append([]*regexp.Regexp(nil), c.Patterns...),
+		mask:     c.Mask,
+	}
+	if len(r.mask) == 0 {
+		r.mask = "***"
+	}
+
+	var quoted []string
+	for _, k := range c.Keys {
+		if len(k) == 0 {
+			continue
+		}
+		r.keys = append(r.keys, strings.ToLower(k))
+		quoted = append(quoted, regexp.QuoteMeta(k))
+	}
+
+	if len(quoted) > 0 {
+		// name, optional closing quote of JSON key and separator
+		re, err := regexp.Compile(`(?i)\b[\w.\-]*(?:` + strings.Join(quoted, "|") + `)"?\s*[:=]\s*`)
+		if err != nil {
+			return nil, ErrInvalidConfig
+		}
+		r.keyRe = re
+	}
+	return r, nil
+}
+
+func (r *redactor) isKey(name string) bool {
+	lower := strings.ToLower(name)
+	for _, k := range r.keys {
+		if strings.HasSuffix(lower, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) text(s string) string {
+	// patterns first not to leave a part of multi-word secret
+	// such as "Authorization: Bearer xxx"
+	for _, re := range r.patterns {
+		if re == RedactPAN {
+			s = re.ReplaceAllStringFunc(s, func(m string) string {
+				if luhn(m) {
+					return r.mask
+				}
+				return m
+			})
+			continue
+		}
+		s = re.ReplaceAllLiteralString(s, r.mask)
+	}
+	if r.keyRe != nil {
+		s = r.maskKeys(s)
+	}
+	return s
+}
+
+// valueEnd matches the end of an unquoted value: the next key,
+// e.g. " user:" of "{Password:a b user:bob}", or a closing bracket.
+var valueEnd = regexp.MustCompile(`[\s,;&]+"?[\w.\-]+"?\s*[:=]|[}\]]`)
+
+// maskKeys masks values following sensitive keys.
+// A quoted value ends at the closing quote, and an unquoted value,
+// which may have spaces like %+v of a struct, ends at valueEnd.
+func (r *redactor) maskKeys(s string) string {
+	var b strings.Builder
+	pos := 0
+	for pos < len(s) {
+		loc := r.keyRe.FindStringIndex(s[pos:])
+		if loc == nil {
+			break
+		}
+		start := pos + loc[1]
+		end := len(s)
+		if start < len(s) && s[start] == '"' {
+			if i := strings.IndexByte(s[start+1:], '"'); i >= 0 {
+				end = start + 1 + i + 1
+			}
+		} else if m := valueEnd.FindStringIndex(s[start:]); m != nil {
+			end = start + m[0]
+		}
+
+		b.WriteString(s[pos:start])
+		if end > start {
+			b.WriteString(r.mask)
+		}
+		pos = end
+	}
+	if pos == 0 {
+		return s
+	}
+	b.WriteString(s[pos:])
+	return b.String()
+}
+
+// value returns masked value and whether it is changed.
+// Strings and rendered values are masked, errors keep their causes.
+func (r *redactor) value(v interface{}) (interface{}, bool) {
+	switch vv := v.(type) {
+	case nil:
+		return v, false
+	case string:
+		s := r.text(vv)
+		return s, s != vv
+	case error:
+		e := r.error(vv, 0)
+		return e, e != vv
+	default:
+		orig := fmt.Sprintf("%+v", v)
+		if s := r.text(orig); s != orig {
+			return s, true
+		}
+		return v, false
+	}
+}
+
+func (r *redactor) error(err error, depth int) error {
+	orig := err.Error()
+	masked := r.text(orig)
+
+	var causes []error
+	changed := masked != orig
+	if depth < maxErrorDepth {
+		for _, c := range describeCauses(err) {
+			rc := r.error(c, depth+1)
+			changed = changed || rc != c
+			causes = append(causes, rc)
+		}
+	}
+
+	if !changed {
+		return err
+	}
+	return &redactedError{msg: masked, causes: causes}
+}
+
+func (r *redactor) redact(rec *Record) {
+	if m, changed := r.value(rec.Msg); changed {
+		rec.Msg = m
+	}
+
+	copied := false
+	for i, f := range rec.Fields {
+		var v interface{}
+		if r.isKey(f.Key) {
+			v = r.mask
+		} else {
+			var changed bool
+			if v, changed = r.value(f.Value); !changed {
+				continue
+			}
+		}
+
+		// fields may be caller's array
+		if !copied {
+			rec.Fields = append([]Field(nil), rec.Fields...)
+			copied = true
+		}
+		rec.Fields[i].Value = v
+	}
+}
+
+// redactedError is an error whose message and causes are masked.
+type redactedError struct {
+	msg    string
+	causes []error
+}
+
+func (e *redactedError) Error() string   { return e.msg }
+func (e *redactedError) Unwrap() []error { return e.causes }
+
+// luhn reports whether digits in s pass Luhn checksum.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	r, err := newRedactor(NewRedactConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type login struct {
+		User     string
+		Password string
+	}
+
+	given := []Field{
+		F("api_key", "k-123"),
+		F("contact", "mail bob@example.com"),
+		F("card", "4111 1111 1111 1111"),
+		F("order", "1234567890123"),
+		Err(fmt.Errorf("auth: %w", errors.New("header Authorization: Bearer abc.def"))),
+	}
+	rec := &Record{Msg: login{User: "bob", Password: "hunter2"}, Fields: given}
+	r.redact(rec)
+
+	if rec.Msg != "{User:bob Password:***}" {
+		t.Errorf("msg: got %v", rec.Msg)
+	}
+
+	want := []string{"***", "mail ***", "***", "1234567890123"}
+	for i, w := range want {
+		if rec.Fields[i].Value != w {
+			t.Errorf("field %s: got %v, want %v", rec.Fields[i].Key, rec.Fields[i].Value, w)
+		}
+	}
+	if given[0].Value != "k-123" {
+		t.Error("redaction changed caller's fields")
+	}
+
+	e, ok := rec.Fields[4].Value.(error)
+	if !ok {
+		t.Fatalf("error field is not an error: %T", rec.Fields[4].Value)
+	}
+	info := describeError(e, 0)
+	if len(info.Causes) != 1 || info.Causes[0].Msg != "header Authorization: ***" {
+		t.Errorf("error: got %+v", info)
+	}
+}
+
+func TestRedactValueWithSpaces(t *testing.T) {
+	r, err := newRedactor(NewRedactConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type login struct {
+		User     string
+		Password string
+		Mode     string
+	}
+
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{login{User: "bob", Password: "correct horse battery staple"}, "{User:bob Password:*** Mode:}"},
+		{map[string]string{"secret": "a b c"}, "map[secret:***]"},
+		{map[string]string{"secret": "a b c", "user": "bob"}, "map[secret:*** user:bob]"},
+		{"token=abc def&user=bob", "token=***&user=bob"},
+		{`{"password":"a b","user":"bob"}`, `{"password":***,"user":"bob"}`},
+		{"password: two words", "password: ***"},
+	}
+	for _, tt := range tests {
+		if got, _ := r.value(tt.value); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
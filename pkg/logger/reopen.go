@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// reopener is implemented by adapters writing to a file.
+type reopener interface {
+	reopen() error
+}
+
+// Reopen for singleton
+func Reopen() error { return lgr.Reopen() }
+
+// Reopen flushes and reopens files of all adapters,
+// e.g. after an external tool such as logrotate moved them.
+// It returns the first error, but tries all adapters.
+func (logger *Logger) Reopen() error {
+	return logger.reopen(nil)
+}
+
+// reopen reopens files of all adapters, and passes each error to onError if not nil.
+func (logger *Logger) reopen(onError ErrorHandler) error {
+	logger.lock.RLock()
+	defer logger.lock.RUnlock()
+
+	logger.flush()
+
+	var first error
+	for _, a := range logger.loadAdapters() {
+		if r, ok := a.(reopener); ok {
+			err := r.reopen()
+			if err == nil {
+				continue
+			}
+			if onError != nil {
+				onError(a.id(), err)
+			}
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// ReopenOnSignal for singleton
+func ReopenOnSignal(sig ...os.Signal) (stop func()) { return lgr.ReopenOnSignal(sig...) }
+
+// ReopenOnSignal calls Reopen whenever one of sig is received.
+// SIGHUP is used if sig is empty.
+// Errors are passed to the handler set by SetErrorHandler.
+// Calling stop stops handling signals.
+func (logger *Logger) ReopenOnSignal(sig ...os.Signal) (stop func()) {
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, sig...)
+
+	go func() {
+		for {
+			select {
+			case <-c:
+				logger.reopen(logger.handleError)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
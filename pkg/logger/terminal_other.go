@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package logger
+
+import "os"
+
+// isTerminal can't tell a terminal on the platform,
+// colors are enabled by FORCE_COLOR only.
+func isTerminal(f *os.File) bool {
+	return false
+}
@@ -0,0 +1,82 @@
+package logger
+
+import "time"
+
+// Timer measures an operation and logs its elapsed time on Stop:
+//
+//	defer logger.Timer("load config").Threshold(200*time.Millisecond, LevelWarning).Stop()
+//
+// The elapsed time is recorded as "elapsed" field of time.Duration.
+type Timer struct {
+	logger    *Logger
+	msg       string
+	fields    []Field
+	start     time.Time
+	level     Level
+	slow      time.Duration
+	slowLevel Level
+	onlySlow  bool
+	stopped   bool
+	elapsed   time.Duration
+}
+
+// StartTimer for singleton
+func StartTimer(msg string, fields ...Field) *Timer { return lgr.Timer(msg, fields...) }
+
+// Timer starts a timer which logs msg and fields at LevelInformation on Stop.
+func (logger *Logger) Timer(msg string, fields ...Field) *Timer {
+	return &Timer{
+		// a child, so that the caller of Stop is reported without singleton wrappers
+		logger: logger.WithCallerSkip(0),
+		msg:    msg,
+		fields: fields,
+		start:  time.Now(),
+		level:  LevelInformation,
+	}
+}
+
+// Level sets the level of the record.
+func (t *Timer) Level(l Level) *Timer {
+	t.level = l
+	return t
+}
+
+// Threshold escalates the record to level l
+// when the elapsed time is d or longer.
+func (t *Timer) Threshold(d time.Duration, l Level) *Timer {
+	t.slow = d
+	t.slowLevel = l
+	return t
+}
+
+// OnlySlow logs only when the elapsed time reaches Threshold.
+func (t *Timer) OnlySlow() *Timer {
+	t.onlySlow = true
+	return t
+}
+
+// Stop logs and returns the elapsed time.
+// Only the first Stop logs, later calls return the same elapsed time.
+func (t *Timer) Stop() time.Duration {
+	if t.stopped {
+		return t.elapsed
+	}
+	t.stopped = true
+	t.elapsed = time.Since(t.start)
+
+	level := t.level
+	if t.slow > 0 && t.elapsed >= t.slow {
+		level = t.slowLevel
+	} else if t.onlySlow {
+		return t.elapsed
+	}
+
+	logger := t.logger
+	if !logger.enabled(level) {
+		return t.elapsed
+	}
+
+	n := len(t.fields)
+	logger.write(level, t.msg, append(t.fields[:n:n], F("elapsed", t.elapsed)))
+	return t.elapsed
+}
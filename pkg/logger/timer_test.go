@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimer(t *testing.T) {
+	var out bytes.Buffer
+
+	l := New("timer", false)
+	c := NewConsoleAdapterConfig()
+	c.Color = false
+	c.Format = "[$slevel] $msg$fields ($file)"
+	c.Writer = &out
+	if err := l.Attach(c); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Timer("fast", F("n", 1)).Level(LevelDebug).Threshold(time.Hour, LevelWarning).Stop()
+	l.Timer("quiet").Threshold(time.Hour, LevelWarning).OnlySlow().Stop()
+
+	slow := l.Timer("slow").Threshold(time.Millisecond, LevelWarning)
+	time.Sleep(2 * time.Millisecond)
+	elapsed := slow.Stop()
+	if elapsed < time.Millisecond || slow.Stop() != elapsed {
+		t.Errorf("elapsed: got %v", elapsed)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), lineFeed), lineFeed)
+	if len(lines) != 2 {
+		t.Fatalf("got %q", out.String())
+	}
+	if !strings.HasPrefix(lines[0], "[DBG] fast n=1 elapsed=") || !strings.HasSuffix(lines[0], "(timer_test.go)") {
+		t.Errorf("fast: got %q", lines[0])
+	}
+	if want := "[WRN] slow elapsed=" + elapsed.String() + " (timer_test.go)"; lines[1] != want {
+		t.Errorf("slow: got %q, want %q", lines[1], want)
+	}
+}